@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"strings"
+	"time"
+
+	"api-scaffolding/internal/backup"
+	"api-scaffolding/internal/database"
+	"api-scaffolding/internal/server"
+)
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [-config file] [command] [flags]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Commands:")
+	fmt.Fprintln(out, "  serve            start the web UI on :4000 (default)")
+	fmt.Fprintln(out, "  scan             introspect a connection (or a -ddl file) and store its metadata")
+	fmt.Fprintln(out, "  drift            compare stored metadata with the live database")
+	fmt.Fprintln(out, "  generate         generate endpoint files for a connection")
+	fmt.Fprintln(out, "  history          list the generation runs of a connection")
+	fmt.Fprintln(out, "  rollback         undo a generation run by id")
+	fmt.Fprintln(out, "  backups          list, diff or purge the backups of a project")
+	fmt.Fprintln(out, "  list-templates   list the visible file templates")
+	fmt.Fprintln(out, "  list-tables      list the tables stored for a connection")
+	fmt.Fprintln(out, "\nGlobal flags:")
+	flag.PrintDefaults()
+}
+
+// runCommand executes a headless subcommand and returns the process exit code.
+func runCommand(srv *server.Server, name string, args []string) int {
+	switch name {
+	case "scan":
+		return cmdScan(srv, args)
+	case "drift":
+		return cmdDrift(srv, args)
+	case "generate":
+		return cmdGenerate(srv, args)
+	case "history":
+		return cmdHistory(srv, args)
+	case "rollback":
+		return cmdRollback(srv, args)
+	case "backups":
+		return cmdBackups(srv, args)
+	case "list-templates":
+		return cmdListTemplates(srv, args)
+	case "list-tables":
+		return cmdListTables(srv, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		return 2
+	}
+}
+
+// connectionFlags registers the -project and -connection flags shared by
+// the commands that work on a single connection.
+func connectionFlags(fs *flag.FlagSet) (project, connection *string) {
+	project = fs.String("project", "", "project name (required)")
+	connection = fs.String("connection", "", "connection name (required)")
+	return project, connection
+}
+
+func parseFlags(fs *flag.FlagSet, args []string, required ...string) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	for _, name := range required {
+		if fs.Lookup(name).Value.String() == "" {
+			fmt.Fprintf(os.Stderr, "%s: -%s is required\n", fs.Name(), name)
+			fs.Usage()
+			return false
+		}
+	}
+	return true
+}
+
+func cmdScan(srv *server.Server, args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	project, connection := connectionFlags(fs)
+	ddlFile := fs.String("ddl", "", "read the tables from a schema script instead of the database")
+	flavor := fs.String("flavor", "", "flavor of the -ddl script: postgres or mysql (detected when empty)")
+	if !parseFlags(fs, args, "project", "connection") {
+		return 2
+	}
+
+	var diff database.SchemaDiff
+	var err error
+	if *ddlFile != "" {
+		var src []byte
+		if src, err = os.ReadFile(*ddlFile); err == nil {
+			diff, err = srv.ScanDDL(*project, *connection, string(src), *flavor)
+		}
+	} else {
+		// Ctrl-C aborts the scan of the live database
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		diff, err = srv.ScanConnection(ctx, *project, *connection)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scan failed: %v\n", err)
+		return 1
+	}
+
+	if diff.IsEmpty() {
+		fmt.Printf("No changes in %s/%s since the last scan\n", *project, *connection)
+		return 0
+	}
+	for _, c := range diff.Changes {
+		fmt.Println(c)
+	}
+	fmt.Printf("%d changes in %d tables of %s/%s\n", len(diff.Changes), len(diff.Tables()), *project, *connection)
+	return 0
+}
+
+// cmdDrift exits with status 1 when the stored metadata is out of date, so it
+// can guard a CI pipeline after migrations.
+func cmdDrift(srv *server.Server, args []string) int {
+	fs := flag.NewFlagSet("drift", flag.ContinueOnError)
+	project, connection := connectionFlags(fs)
+	if !parseFlags(fs, args, "project", "connection") {
+		return 2
+	}
+
+	// Ctrl-C aborts the scan of the live database
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := srv.CheckDrift(ctx, *project, *connection)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "drift check failed: %v\n", err)
+		return 1
+	}
+
+	if report.Diff.IsEmpty() {
+		fmt.Printf("No drift in %s/%s\n", *project, *connection)
+		return 0
+	}
+	for _, c := range report.Diff.Changes {
+		fmt.Println(c)
+	}
+	for _, f := range report.StaleFiles {
+		fmt.Printf("stale  %s\n", f.File)
+	}
+	fmt.Printf("%d changes in %d tables, %d stale files\n", len(report.Diff.Changes), len(report.Diff.Tables()), len(report.StaleFiles))
+	return 1
+}
+
+func cmdGenerate(srv *server.Server, args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	project, connection := connectionFlags(fs)
+	subsystem := fs.String("subsystem", "public", "subsystem used in paths and routes")
+	tables := fs.String("tables", "*", "comma separated table names, * for all")
+	templates := fs.String("templates", "*", "comma separated file_template ids, * for all visible")
+	dryRun := fs.Bool("dry-run", false, "print a diff against the current files instead of writing them")
+	if !parseFlags(fs, args, "project", "connection") {
+		return 2
+	}
+
+	results, err := srv.Generate(server.GenerateRequest{
+		ProjectName: *project,
+		Connection:  *connection,
+		Subsystem:   *subsystem,
+		Tables:      splitList(*tables),
+		TemplateIDs: splitList(*templates),
+		DryRun:      *dryRun,
+		User:        currentUser(),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "generate failed: %v\n", err)
+		return 1
+	}
+
+	if *dryRun {
+		return printPreview(results)
+	}
+
+	failed, skipped := 0, 0
+	for _, r := range results {
+		if r.Status == "ok" {
+			fmt.Printf("ok     %s\n", r.File)
+			continue
+		}
+		if r.Status == "skipped" {
+			skipped++
+		} else {
+			failed++
+		}
+		fmt.Printf("%-6s %s: %s\n", r.Status, r.File, r.Message)
+	}
+	fmt.Printf("%d files generated, %d skipped, %d errors\n", len(results)-failed-skipped, skipped, failed)
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printPreview prints the outcome of a dry run followed by the diffs.
+func printPreview(results []server.GenerateResult) int {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Status]++
+		if r.Message != "" {
+			fmt.Printf("%-10s %s: %s\n", r.Status, r.File, r.Message)
+			continue
+		}
+		fmt.Printf("%-10s %s\n", r.Status, r.File)
+	}
+	for _, r := range results {
+		if r.Diff != "" {
+			fmt.Print("\n" + r.Diff)
+		}
+	}
+	fmt.Printf("\n%d new, %d modified, %d unchanged, %d skipped, %d conflicts, %d errors\n", counts["new"], counts["modified"], counts["unchanged"], counts["skipped"], counts["conflict"], counts["error"])
+
+	if counts["error"] > 0 || counts["conflict"] > 0 {
+		return 1
+	}
+	return 0
+}
+
+func cmdHistory(srv *server.Server, args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	project, connection := connectionFlags(fs)
+	if !parseFlags(fs, args, "project", "connection") {
+		return 2
+	}
+
+	generations, err := srv.ListGenerations(*project, *connection)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history failed: %v\n", err)
+		return 1
+	}
+
+	for _, g := range generations {
+		state := ""
+		if g.RolledBackAt.Valid {
+			state = "  (rolled back)"
+		}
+		fmt.Printf("%-5d %s  %-12s %-10s %3d files  tables=%s templates=%s%s\n",
+			g.ID, g.GeneratedAt.Format("2006-01-02 15:04:05"), g.GeneratedBy.String, g.Subsystem.String,
+			len(g.Files), g.Tables.String, g.Templates.String, state)
+	}
+	return 0
+}
+
+func cmdRollback(srv *server.Server, args []string) int {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	id := fs.Int("id", 0, "generation id (required), see the history command")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	if *id <= 0 {
+		fmt.Fprintln(os.Stderr, "rollback: -id is required")
+		fs.Usage()
+		return 2
+	}
+
+	gen, err := srv.Rollback(*id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rollback failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Rolled back generation %d (%d files)\n", gen.ID, len(gen.Files))
+	return 0
+}
+
+// cmdBackups runs "backups list", "backups diff -path <backup>" or
+// "backups purge [-keep n] [-max-age-days n]" for a project.
+func cmdBackups(srv *server.Server, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "backups: expected list, diff or purge")
+		return 2
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("backups "+action, flag.ContinueOnError)
+	project := fs.String("project", "", "project name (required)")
+	path := fs.String("path", "", "backup to compare with the current file (diff)")
+	keep := fs.Int("keep", backup.DefaultPolicy.KeepCount, "backups kept per file, 0 keeps all (purge)")
+	maxAgeDays := fs.Int("max-age-days", int(backup.DefaultPolicy.MaxAge/(24*time.Hour)), "remove older backups, 0 keeps all (purge)")
+	if !parseFlags(fs, args[1:], "project") {
+		return 2
+	}
+
+	switch action {
+	case "list":
+		entries, err := srv.ListBackups(*project)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "backups list failed: %v\n", err)
+			return 1
+		}
+		for _, e := range entries {
+			legacy := ""
+			if e.Legacy {
+				legacy = "  (legacy)"
+			}
+			fmt.Printf("%s  %8d  %s -> %s%s\n", e.Time.Format("2006-01-02 15:04:05"), e.Size, e.Path, e.File, legacy)
+		}
+		return 0
+	case "diff":
+		if *path == "" {
+			fmt.Fprintln(os.Stderr, "backups diff: -path is required")
+			return 2
+		}
+		diff, err := srv.BackupDiff(*project, *path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "backups diff failed: %v\n", err)
+			return 1
+		}
+		fmt.Print(diff)
+		return 0
+	case "purge":
+		removed, err := srv.PurgeBackups(*project, *keep, *maxAgeDays)
+		for _, e := range removed {
+			fmt.Printf("removed  %s\n", e.Path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "backups purge failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%d backups removed\n", len(removed))
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "backups: unknown action %q, expected list, diff or purge\n", action)
+		return 2
+	}
+}
+
+func cmdListTemplates(srv *server.Server, args []string) int {
+	fs := flag.NewFlagSet("list-templates", flag.ContinueOnError)
+	if !parseFlags(fs, args) {
+		return 2
+	}
+
+	templates, err := srv.ListFileTemplates()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "list-templates failed: %v\n", err)
+		return 1
+	}
+
+	for _, ft := range templates {
+		fmt.Printf("%-4d %-16s %-14s %s%s\n", ft.ID, ft.Category.String, ft.Name.String, ft.Path.String, ft.File.String)
+	}
+	return 0
+}
+
+func cmdListTables(srv *server.Server, args []string) int {
+	fs := flag.NewFlagSet("list-tables", flag.ContinueOnError)
+	project, connection := connectionFlags(fs)
+	if !parseFlags(fs, args, "project", "connection") {
+		return 2
+	}
+
+	tables, err := srv.ListTables(*project, *connection)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "list-tables failed: %v\n", err)
+		return 1
+	}
+
+	for _, t := range tables {
+		fmt.Printf("%s.%s\t%s\n", t.DbSchema, t.TableName, t.EntityName.String)
+	}
+	return 0
+}
+
+// currentUser names the operating system user running the command.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func splitList(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
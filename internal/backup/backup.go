@@ -0,0 +1,351 @@
+// Package backup keeps the previous versions of generated files.
+//
+// Every writer of generated files moves the file it is about to replace into
+// a backup tree (by default a ".backups" directory at the project root) that
+// mirrors the layout of the project, named <file>.<timestamp>.bak. Retention
+// by count and age is applied per file each time a backup is taken, and on
+// demand with Purge.
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat is the timestamp used in every backup name.
+const TimeFormat = "20060102150405"
+
+// Policy configures where backups go and how long they are kept.
+type Policy struct {
+	Dir       string        // backup tree, relative to the store root or absolute
+	KeepCount int           // backups kept per file, 0 keeps all
+	MaxAge    time.Duration // backups older than this are removed, 0 keeps all
+}
+
+// DefaultPolicy is used by every writer of generated files. main sets it from
+// the configuration.
+var DefaultPolicy = Policy{Dir: ".backups", KeepCount: 10}
+
+// Entry is one backup of a file.
+type Entry struct {
+	File   string    `json:"file"` // the file that was backed up
+	Path   string    `json:"path"` // the backup itself
+	Time   time.Time `json:"time"`
+	Size   int64     `json:"size"`
+	Legacy bool      `json:"legacy"` // .bak left next to the file by older versions
+	seq    int       // orders backups taken within the same second
+}
+
+// Store manages the backups of the files under a root directory, usually the
+// rootdir of a project.
+type Store struct {
+	root   string
+	policy Policy
+	kept   map[string]bool
+}
+
+// NewStore returns the backup store of a root directory. Files outside the
+// root cannot be backed up by the store.
+func NewStore(root string, policy Policy) *Store {
+	if policy.Dir == "" {
+		policy.Dir = ".backups"
+	}
+	return &Store{root: root, policy: policy}
+}
+
+// Keep exempts backups from retention: Backup and Purge never remove them.
+// Used for the backups a generation run can still be rolled back to.
+func (s *Store) Keep(paths []string) {
+	s.kept = make(map[string]bool, len(paths))
+	for _, p := range paths {
+		s.kept[filepath.Clean(p)] = true
+	}
+}
+
+// Policy returns the policy of the store.
+func (s *Store) Policy() Policy {
+	return s.policy
+}
+
+// Dir returns the directory holding the backups.
+func (s *Store) Dir() string {
+	if filepath.IsAbs(s.policy.Dir) {
+		return s.policy.Dir
+	}
+	return filepath.Join(s.root, s.policy.Dir)
+}
+
+var (
+	// <file>.<timestamp>[-n].bak, as written by the store
+	backupNameRe = regexp.MustCompile(`^(.+)\.(\d{14})(?:-(\d+))?\.bak$`)
+	// <file>.<date or timestamp>.bak, as left by older versions
+	legacyNameRe = regexp.MustCompile(`^(.+)\.(\d{8}|\d{14})\.bak$`)
+)
+
+// Backup moves path into the backup tree and applies the retention policy to
+// the backups of that file. It returns the backup path, or "" when path does
+// not exist.
+func (s *Store) Backup(path string) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	rel, err := s.relative(path)
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Join(s.Dir(), rel) + "." + time.Now().Format(TimeFormat)
+	backupPath := base + ".bak"
+	if taken, _ := filepath.Glob(base + "*.bak"); len(taken) > 0 {
+		// Same second as a previous backup: number it after the last one
+		last := 0
+		for _, t := range taken {
+			if m := backupNameRe.FindStringSubmatch(filepath.Base(t)); m != nil {
+				if n, _ := strconv.Atoi(m[3]); n > last {
+					last = n
+				}
+			}
+		}
+		backupPath = fmt.Sprintf("%s-%d.bak", base, last+1)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return "", fmt.Errorf("error creating backup directory: %v", err)
+	}
+	if err := os.Rename(path, backupPath); err != nil {
+		return "", fmt.Errorf("error backing up %s: %v", path, err)
+	}
+
+	entries, err := s.backupsOf(path)
+	if err != nil {
+		return backupPath, err
+	}
+	_, err = s.prune(entries, s.policy)
+	return backupPath, err
+}
+
+// List returns every backup of the store, including the legacy .bak files
+// found next to the generated files, grouped by file and newest first.
+func (s *Store) List() ([]Entry, error) {
+	var entries []Entry
+
+	dir := s.Dir()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		m := backupNameRe.FindStringSubmatch(info.Name())
+		if m == nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filepath.Join(filepath.Dir(path), m[1]))
+		if err != nil {
+			return nil
+		}
+		t, _ := time.ParseInLocation(TimeFormat, m[2], time.Local)
+		seq, _ := strconv.Atoi(m[3])
+		entries = append(entries, Entry{
+			File: filepath.Join(s.root, rel),
+			Path: path,
+			Time: t,
+			Size: info.Size(),
+			seq:  seq,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	legacy, err := s.legacyBackups()
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, legacy...)
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+// legacyBackups finds the .bak files older versions left next to the
+// generated files.
+func (s *Store) legacyBackups() ([]Entry, error) {
+	if s.root == "" {
+		return nil, nil
+	}
+
+	var entries []Entry
+	backupDir := filepath.Clean(s.Dir())
+	err := filepath.Walk(s.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == s.root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			if path != s.root && (filepath.Clean(path) == backupDir || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		m := legacyNameRe.FindStringSubmatch(info.Name())
+		if m == nil {
+			return nil
+		}
+		layout := TimeFormat
+		if len(m[2]) == 8 {
+			layout = "20060102"
+		}
+		t, _ := time.ParseInLocation(layout, m[2], time.Local)
+		entries = append(entries, Entry{
+			File:   legacyOriginal(filepath.Join(filepath.Dir(path), m[1])),
+			Path:   path,
+			Time:   t,
+			Size:   info.Size(),
+			Legacy: true,
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// legacyOriginal guesses the file a legacy backup belongs to: one of the
+// older writers dropped the extension of the original file.
+func legacyOriginal(file string) string {
+	if filepath.Ext(file) != "" {
+		return file
+	}
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		if fileExists(file + ext) {
+			return file + ext
+		}
+	}
+	return file
+}
+
+// Purge applies a retention policy to every backup of the store, legacy ones
+// included, and returns the removed backups.
+func (s *Store) Purge(policy Policy) ([]Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	byFile := make(map[string][]Entry)
+	var files []string
+	for _, e := range entries {
+		if _, ok := byFile[e.File]; !ok {
+			files = append(files, e.File)
+		}
+		byFile[e.File] = append(byFile[e.File], e)
+	}
+
+	var removed []Entry
+	for _, file := range files {
+		r, err := s.prune(byFile[file], policy)
+		removed = append(removed, r...)
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// Find returns the backup stored at path, which must belong to the store.
+func (s *Store) Find(path string) (Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, e := range entries {
+		if e.Path == filepath.Clean(path) {
+			return e, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("backup %s not found", path)
+}
+
+// backupsOf returns the backups of a single file in the backup tree.
+func (s *Store) backupsOf(path string) ([]Entry, error) {
+	rel, err := s.relative(path)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(s.Dir(), rel) + ".*.bak")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, m := range matches {
+		parts := backupNameRe.FindStringSubmatch(filepath.Base(m))
+		if parts == nil || parts[1] != filepath.Base(rel) {
+			continue
+		}
+		t, _ := time.ParseInLocation(TimeFormat, parts[2], time.Local)
+		seq, _ := strconv.Atoi(parts[3])
+		entries = append(entries, Entry{File: path, Path: m, Time: t, seq: seq})
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// prune removes the backups of one file, newest first, beyond the policy.
+// Kept backups stay, but count towards KeepCount.
+func (s *Store) prune(entries []Entry, policy Policy) ([]Entry, error) {
+	var removed []Entry
+	for i, e := range entries {
+		if s.kept[filepath.Clean(e.Path)] {
+			continue
+		}
+		tooMany := policy.KeepCount > 0 && i >= policy.KeepCount
+		tooOld := policy.MaxAge > 0 && time.Since(e.Time) > policy.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(e.Path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("error removing backup %s: %v", e.Path, err)
+		}
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+// relative maps a file to its place in the backup tree.
+func (s *Store) relative(path string) (string, error) {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside %s", path, s.root)
+	}
+	return rel, nil
+}
+
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].File != entries[j].File {
+			return entries[i].File < entries[j].File
+		}
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.After(entries[j].Time)
+		}
+		return entries[i].seq > entries[j].seq
+	})
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
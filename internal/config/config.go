@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -24,6 +25,11 @@ type Config struct {
 	ProjectTables    []string
 	ProjectRelations []string
 	DBSchema         string
+	BackupDir        string
+	BackupKeep       int
+	BackupMaxAgeDays int
+	ScanWorkers      int
+	ScanTimeout      int // seconds, 0 = no limit
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -49,6 +55,11 @@ func LoadConfig(configPath string) (*Config, error) {
 		ProjectFileTypes: strings.ToLower(getEnv("PROJECT_FILE_TYPES", "yaml")),
 		ProjectDir:       getEnv("PROJECT_DIR", "./apis/"),
 		ProjectSchema:    getEnv("PROJECT_SCHEMA", "public"),
+		BackupDir:        getEnv("BACKUP_DIR", ".backups"),
+		BackupKeep:       getEnvInt("BACKUP_KEEP", 10),
+		BackupMaxAgeDays: getEnvInt("BACKUP_MAX_AGE_DAYS", 0),
+		ScanWorkers:      getEnvInt("SCAN_WORKERS", 8),
+		ScanTimeout:      getEnvInt("SCAN_TIMEOUT", 0),
 	}
 
 	// Parsear tablas
@@ -82,6 +93,13 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func splitAndTrim(str, sep string) []string {
 	if str == "" {
 		return []string{}
@@ -0,0 +1,399 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CheckRules describes the Enum, Min, Max and Pattern of a column in one line,
+// e.g. "enum(a, b) min 0 max 10". It is empty for unrestricted columns.
+func (c Column) CheckRules() string {
+	var parts []string
+	if len(c.Enum) > 0 {
+		parts = append(parts, "enum("+strings.Join(c.Enum, ", ")+")")
+	}
+	if c.Min != nil {
+		parts = append(parts, "min "+FormatBound(*c.Min))
+	}
+	if c.Max != nil {
+		parts = append(parts, "max "+FormatBound(*c.Max))
+	}
+	if c.Pattern != "" {
+		parts = append(parts, "pattern "+c.Pattern)
+	}
+	return strings.Join(parts, " ")
+}
+
+// FormatBound formats a Min or Max without exponent ("1000000", "0.5").
+func FormatBound(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ParseBound is the reverse of FormatBound; empty text means no bound.
+func ParseBound(s string) *float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// setPattern returns the pattern accepted by a MySQL SET column: a comma
+// separated list of its members.
+func setPattern(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+	member := "(" + strings.Join(quoted, "|") + ")"
+	return fmt.Sprintf("^(%s(,%s)*)?$", member, member)
+}
+
+// applyEnumType sets the rules of a MySQL ENUM('a', 'b') or SET('a', 'b')
+// column from its full type.
+func applyEnumType(col *Column, columnType string) {
+	tokens, err := tokenizeDDL(columnType, FlavorMySQL)
+	if err != nil || len(tokens) < 3 || !tokens[1].is("(") {
+		return
+	}
+	setEnumArgs(col, tokens[0].text, tokens[2:])
+}
+
+// setEnumArgs gives an enum the strings of its type arguments as values, and
+// a set the pattern of a list of its members.
+func setEnumArgs(col *Column, kind string, args []ddlToken) {
+	var values []string
+	for _, t := range args {
+		if t.kind == tokString {
+			values = append(values, t.text)
+		}
+	}
+	switch strings.ToLower(kind) {
+	case "enum":
+		setEnum(col, values)
+	case "set":
+		col.Pattern = setPattern(values)
+	}
+}
+
+// applyCheck reads a CHECK expression and stores what it says about single
+// columns of the table in their Enum, Min, Max and Pattern. Conditions it
+// doesn't understand (functions, several columns, NOT...) are left out: the
+// database still enforces them.
+func applyCheck(table *Table, expr, flavor string) {
+	tokens, err := tokenizeDDL(expr, flavor)
+	if err != nil {
+		return
+	}
+	if len(tokens) > 0 && tokens[0].is("CHECK") {
+		tokens = tokens[1:]
+	}
+	applyConditions(table, stripCasts(tokens))
+}
+
+// applyDomainCheck reads a CHECK of a domain, written on VALUE, into the
+// rules of a column declared with that domain.
+func applyDomainCheck(col *Column, expr, flavor string) {
+	value := *col
+	value.Name = "value"
+	table := &Table{Columns: []Column{value}}
+	applyCheck(table, expr, flavor)
+	value = table.Columns[0]
+	col.Enum, col.Min, col.Max, col.Pattern = value.Enum, value.Min, value.Max, value.Pattern
+}
+
+// applyConditions handles the conjunctions of a CHECK and the disjunctions of
+// equalities on one column (x = 'a' OR x = 'b'), which make an enum.
+func applyConditions(table *Table, tokens []ddlToken) {
+	tokens = unwrapParens(tokens)
+	if parts := splitOnWord(tokens, "AND"); len(parts) > 1 {
+		for _, part := range parts {
+			applyConditions(table, part)
+		}
+		return
+	}
+
+	if parts := splitOnWord(tokens, "OR"); len(parts) > 1 {
+		var column string
+		var values []string
+		for _, part := range parts {
+			name, op, args := condition(unwrapParens(part))
+			if op != "=" || len(args) != 1 || (column != "" && !strings.EqualFold(column, name)) {
+				return
+			}
+			column = name
+			values = append(values, args[0].text)
+		}
+		if col := findColumn(table, column); col != nil {
+			setEnum(col, values)
+		}
+		return
+	}
+
+	name, op, args := condition(tokens)
+	col := findColumn(table, name)
+	if col == nil || len(args) == 0 {
+		return
+	}
+
+	switch op {
+	case "IN", "ANY":
+		var values []string
+		for _, a := range args {
+			values = append(values, a.text)
+		}
+		setEnum(col, values)
+	case "=":
+		if args[0].kind == tokString {
+			setEnum(col, []string{args[0].text})
+		} else {
+			setBound(col, ">=", args[0].text)
+			setBound(col, "<=", args[0].text)
+		}
+	case ">", ">=", "<", "<=":
+		if args[0].kind == tokNumber {
+			setBound(col, op, args[0].text)
+		}
+	case "BETWEEN":
+		if len(args) == 2 && args[0].kind == tokNumber && args[1].kind == tokNumber {
+			setBound(col, ">=", args[0].text)
+			setBound(col, "<=", args[1].text)
+		}
+	case "~", "REGEXP", "RLIKE", "REGEXP_LIKE":
+		if args[0].kind == tokString {
+			col.Pattern = args[0].text
+		}
+	case "LIKE":
+		if args[0].kind == tokString {
+			col.Pattern = likePattern(args[0].text)
+		}
+	}
+}
+
+// condition reads a simple condition as column, operator and literal
+// arguments. Parentheses, ARRAY[...] and the literal order are not relevant
+// here, so "(x) = ANY (ARRAY['a', 'b'])", "x IN ('a', 'b')", "0 <= x" and
+// "regexp_like(x, '^a')" all work. An empty column means it isn't one.
+func condition(tokens []ddlToken) (string, string, []ddlToken) {
+	var flat []ddlToken
+	for _, t := range tokens {
+		if !t.is("(") && !t.is(")") && !t.is("[") && !t.is("]") && !t.is("ARRAY") {
+			flat = append(flat, t)
+		}
+	}
+	if len(flat) < 3 {
+		return "", "", nil
+	}
+
+	// regexp_like(x, 'pattern')
+	if flat[0].is("REGEXP_LIKE") && len(flat) >= 4 && flat[2].is(",") {
+		return flat[1].text, "REGEXP_LIKE", literals(flat[3:4])
+	}
+
+	if flat[0].kind != tokWord && flat[0].kind != tokIdent {
+		// Literal first: 0 <= x is x >= 0
+		column := flat[len(flat)-1]
+		if column.kind != tokWord && column.kind != tokIdent {
+			return "", "", nil
+		}
+		i := 0
+		for i < len(flat) && !(flat[i].kind == tokPunct && strings.Contains("<>=", flat[i].text)) {
+			i++
+		}
+		args := literals(flat[:i])
+		op := ""
+		for _, t := range flat[i : len(flat)-1] {
+			op += t.text
+		}
+		flipped := map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "=": "="}[op]
+		if len(args) != 1 || flipped == "" {
+			return "", "", nil
+		}
+		return column.text, flipped, args
+	}
+
+	column, flat := flat[0], flat[1:]
+	var op string
+	if flat[0].kind == tokWord {
+		op = strings.ToUpper(flat[0].text)
+		flat = flat[1:]
+	} else {
+		for len(flat) > 0 && flat[0].kind == tokPunct && strings.Contains("<>=!~", flat[0].text) {
+			op += flat[0].text
+			flat = flat[1:]
+		}
+	}
+	if op == "=" && len(flat) > 0 && flat[0].is("ANY") {
+		op, flat = "ANY", flat[1:]
+	}
+
+	args := literals(flat)
+	if args == nil {
+		return "", "", nil
+	}
+	return column.text, op, args
+}
+
+// literals returns the strings and (signed) numbers of a list separated by
+// commas, or by AND for BETWEEN. Anything else makes it nil.
+func literals(tokens []ddlToken) []ddlToken {
+	var args []ddlToken
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.is(",") || t.is("AND"):
+		case t.kind == tokString || t.kind == tokNumber:
+			args = append(args, t)
+		case t.is("-") && i+1 < len(tokens) && tokens[i+1].kind == tokNumber:
+			i++
+			args = append(args, ddlToken{kind: tokNumber, text: "-" + tokens[i].text})
+		default:
+			return nil
+		}
+	}
+	return args
+}
+
+func setEnum(col *Column, values []string) {
+	if len(col.Enum) == 0 {
+		col.Enum = values
+		return
+	}
+	// Both an enum type and a CHECK: only the values allowed by both
+	var common []string
+	for _, v := range col.Enum {
+		for _, w := range values {
+			if v == w {
+				common = append(common, v)
+				break
+			}
+		}
+	}
+	col.Enum = common
+}
+
+// setBound narrows Min or Max. Strict comparisons on integer columns move
+// the bound by one; on other types they are taken as inclusive.
+func setBound(col *Column, op, text string) {
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return
+	}
+	integer := isIntegerType(col.DataType) && v == float64(int64(v))
+	switch op {
+	case ">", ">=":
+		if op == ">" && integer {
+			v++
+		}
+		if col.Min == nil || v > *col.Min {
+			col.Min = &v
+		}
+	case "<", "<=":
+		if op == "<" && integer {
+			v--
+		}
+		if col.Max == nil || v < *col.Max {
+			col.Max = &v
+		}
+	}
+}
+
+func isIntegerType(dataType string) bool {
+	t := strings.ToLower(strings.TrimSpace(dataType))
+	if i := strings.IndexAny(t, " ("); i >= 0 {
+		t = t[:i]
+	}
+	switch t {
+	case "int", "integer", "bigint", "smallint", "tinyint", "mediumint",
+		"int2", "int4", "int8", "serial", "bigserial", "smallserial":
+		return true
+	}
+	return false
+}
+
+// likePattern translates a LIKE pattern into a regular expression.
+func likePattern(like string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range like {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// castTypeWords continue a type name after a cast ("::character varying",
+// "::timestamp without time zone").
+var castTypeWords = map[string]bool{"VARYING": true, "PRECISION": true, "WITHOUT": true, "WITH": true, "TIME": true, "ZONE": true}
+
+// stripCasts removes the Postgres casts (x::text, '{a}'::text[]) and the
+// MySQL charset introducers (_utf8mb4'a') that catalogs add to CHECK
+// expressions.
+func stripCasts(tokens []ddlToken) []ddlToken {
+	var out []ddlToken
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.is(":") && i+2 < len(tokens) && tokens[i+1].is(":") {
+			i += 2
+			for i+1 < len(tokens) && tokens[i+1].kind == tokWord && castTypeWords[strings.ToUpper(tokens[i+1].text)] {
+				i++
+			}
+			if i+2 < len(tokens) && tokens[i+1].is("(") && tokens[i+2].kind == tokNumber {
+				if end := matchParen(tokens, i+1); end > 0 {
+					i = end
+				}
+			}
+			for i+2 < len(tokens) && tokens[i+1].is("[") && tokens[i+2].is("]") {
+				i += 2
+			}
+			continue
+		}
+		if t.kind == tokWord && strings.HasPrefix(t.text, "_") && i+1 < len(tokens) && tokens[i+1].kind == tokString && tokens[i+1].start == t.end {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// unwrapParens removes the parentheses around a whole expression.
+func unwrapParens(tokens []ddlToken) []ddlToken {
+	for len(tokens) > 1 && tokens[0].is("(") && matchParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+	return tokens
+}
+
+// splitOnWord splits tokens on a keyword outside parentheses. The AND of
+// "BETWEEN a AND b" doesn't split.
+func splitOnWord(tokens []ddlToken, word string) [][]ddlToken {
+	var parts [][]ddlToken
+	depth, start, between := 0, 0, false
+	for i, t := range tokens {
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")"):
+			depth--
+		case depth == 0 && t.is("BETWEEN"):
+			between = true
+		case depth == 0 && t.is(word):
+			if word == "AND" && between {
+				between = false
+				continue
+			}
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
@@ -0,0 +1,1224 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DDL flavors understood by ParseDDL.
+const (
+	FlavorPostgres = "postgres"
+	FlavorMySQL    = "mysql"
+)
+
+// DetectDDLFlavor guesses the flavor of a DDL script: backquoted identifiers
+// and MySQL table options win, anything else is read as Postgres.
+func DetectDDLFlavor(src string) string {
+	if strings.Contains(src, "`") || mysqlHintRe.MatchString(src) {
+		return FlavorMySQL
+	}
+	return FlavorPostgres
+}
+
+var mysqlHintRe = regexp.MustCompile(`(?i)\)\s*ENGINE\s*=|\bAUTO_INCREMENT\b`)
+
+// ParseDDL reads the tables of a schema script: CREATE TABLE statements,
+// ALTER TABLE ... ADD [CONSTRAINT] PRIMARY KEY / FOREIGN KEY / UNIQUE / COLUMN,
+// CREATE [UNIQUE] INDEX, CREATE [MATERIALIZED] VIEW and COMMENT ON TABLE /
+// VIEW / COLUMN. Every other statement is skipped. An empty flavor
+// is detected from the script. Data types are normalized to the names the
+// information_schema of the flavor reports, so a parsed file and a live scan
+// of the same schema compare equal. Tables keep the schema they are
+// qualified with, or "" when unqualified, and come back sorted by name.
+// Foreign keys keep the schema of the referenced table the same way.
+func ParseDDL(src, flavor string) ([]Table, error) {
+	if flavor == "" {
+		flavor = DetectDDLFlavor(src)
+	}
+	if flavor != FlavorPostgres && flavor != FlavorMySQL {
+		return nil, fmt.Errorf("unsupported DDL flavor: %s", flavor)
+	}
+
+	tokens, err := tokenizeDDL(src, flavor)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ddlParser{src: src, flavor: flavor, enums: make(map[string][]string), domains: make(map[string]Column)}
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && !tokens[i].is(";") {
+			continue
+		}
+		if i > start {
+			if err := p.statement(tokens[start:i]); err != nil {
+				return nil, fmt.Errorf("line %d: %v", tokens[start].line, err)
+			}
+		}
+		start = i + 1
+	}
+
+	p.resolveReferences()
+	sort.SliceStable(p.tables, func(i, j int) bool {
+		return strings.ToLower(p.tables[i].Name) < strings.ToLower(p.tables[j].Name)
+	})
+
+	tables := make([]Table, len(p.tables))
+	for i, t := range p.tables {
+		tables[i] = *t
+	}
+	return tables, nil
+}
+
+// TablesInSchemas keeps the tables of a list of schemas and sets the schema
+// of the unqualified ones to the first of the list, like a search_path. An
+// unqualified foreign key points to the table of that name in the schema of
+// the key, else in the first schema.
+func TablesInSchemas(tables []Table, schemas []string) []Table {
+	if len(schemas) == 0 {
+		return nil
+	}
+	var out []Table
+	for _, t := range tables {
+		if t.Schema == "" {
+			t.Schema = schemas[0]
+		}
+		for _, schema := range schemas {
+			if strings.EqualFold(t.Schema, schema) {
+				t.Schema = schema
+				out = append(out, t)
+				break
+			}
+		}
+	}
+
+	kept := make(map[string]bool, len(out))
+	for _, t := range out {
+		kept[TableKey(t.Schema, t.Name)] = true
+	}
+	for i, t := range out {
+		// The keys are shared with the parsed tables: copy before changing them
+		fks := append([]ForeignKey(nil), t.ForeignKeys...)
+		for k, fk := range fks {
+			switch {
+			case fk.ReferencedSchema != "":
+			case kept[TableKey(t.Schema, fk.ReferencedTable)]:
+				fks[k].ReferencedSchema = t.Schema
+			default:
+				fks[k].ReferencedSchema = schemas[0]
+			}
+		}
+		out[i].ForeignKeys = fks
+	}
+	return out
+}
+
+// --- tokenizer ---
+
+type ddlTokenKind int
+
+const (
+	tokWord   ddlTokenKind = iota // unquoted identifier or keyword
+	tokIdent                      // quoted identifier
+	tokString                     // string literal, value unquoted
+	tokNumber                     // numeric literal
+	tokPunct                      // any other single character
+)
+
+type ddlToken struct {
+	kind       ddlTokenKind
+	text       string // words as written, strings and identifiers unquoted
+	start, end int    // byte offsets in the source
+	line       int
+}
+
+// is reports whether the token is the given punctuation or keyword.
+func (t ddlToken) is(s string) bool {
+	if t.kind == tokPunct {
+		return t.text == s
+	}
+	return t.kind == tokWord && strings.EqualFold(t.text, s)
+}
+
+var dollarTagRe = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+func tokenizeDDL(src, flavor string) ([]ddlToken, error) {
+	var tokens []ddlToken
+	line := 1
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+
+		case strings.HasPrefix(src[i:], "--") || (c == '#' && flavor == FlavorMySQL):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end+2], "\n")
+			i += 2 + end + 2
+
+		case c == '\'' || (c == '"' && flavor == FlavorMySQL):
+			text, n, err := scanQuoted(src[i:], c, flavor == FlavorMySQL)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			tokens = append(tokens, ddlToken{tokString, text, i, i + n, line})
+			line += strings.Count(src[i:i+n], "\n")
+			i += n
+
+		case c == '"' || c == '`':
+			text, n, err := scanQuoted(src[i:], c, false)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			tokens = append(tokens, ddlToken{tokIdent, text, i, i + n, line})
+			i += n
+
+		case c == '$' && dollarTagRe.MatchString(src[i:]):
+			tag := dollarTagRe.FindString(src[i:])
+			end := strings.Index(src[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated %s quote", line, tag)
+			}
+			n := len(tag) + end + len(tag)
+			tokens = append(tokens, ddlToken{tokString, src[i+len(tag) : i+len(tag)+end], i, i + n, line})
+			line += strings.Count(src[i:i+n], "\n")
+			i += n
+
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, ddlToken{tokNumber, src[i:j], i, j, line})
+			i = j
+
+		case isWordByte(c):
+			j := i
+			for j < len(src) && (isWordByte(src[j]) || src[j] >= '0' && src[j] <= '9' || src[j] == '$') {
+				j++
+			}
+			tokens = append(tokens, ddlToken{tokWord, src[i:j], i, j, line})
+			i = j
+
+		default:
+			tokens = append(tokens, ddlToken{tokPunct, string(c), i, i + 1, line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// scanQuoted reads a quoted literal or identifier starting at s[0], where a
+// doubled quote stands for itself. It returns the unquoted text and the
+// length consumed.
+func scanQuoted(s string, quote byte, backslash bool) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case backslash && s[i] == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+			b.WriteByte(quote)
+		case s[i] == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated %c quote", quote)
+}
+
+// --- parser ---
+
+type ddlParser struct {
+	src    string
+	flavor string
+	tables []*Table
+	// foreign keys waiting for the primary key of the referenced table
+	pending []pendingReference
+	// CREATE TYPE ... AS ENUM values, by lower-case type name
+	enums map[string][]string
+	// CREATE DOMAIN base types and rules, by lower-case domain name
+	domains map[string]Column
+}
+
+type pendingReference struct {
+	table  *Table
+	index  int // in table.ForeignKeys
+	schema string
+}
+
+// columnKeywords end the data type of a column and, at depth 0, its default.
+var columnKeywords = map[string]bool{
+	"NOT": true, "NULL": true, "DEFAULT": true, "PRIMARY": true, "REFERENCES": true,
+	"UNIQUE": true, "CHECK": true, "CONSTRAINT": true, "COMMENT": true, "COLLATE": true,
+	"AUTO_INCREMENT": true, "GENERATED": true, "ON": true, "AS": true, "KEY": true,
+	"VISIBLE": true, "INVISIBLE": true, "STORED": true, "VIRTUAL": true, "IDENTITY": true,
+}
+
+func (p *ddlParser) statement(tokens []ddlToken) error {
+	switch {
+	case tokens[0].is("CREATE"):
+		i := 1
+		for i < len(tokens) && isAnyWord(tokens[i], "GLOBAL", "LOCAL", "TEMP", "TEMPORARY", "UNLOGGED") {
+			i++
+		}
+		if i < len(tokens) && tokens[i].is("TABLE") {
+			return p.createTable(tokens, i+1)
+		}
+		if i+1 < len(tokens) && tokens[i].is("UNIQUE") && tokens[i+1].is("INDEX") {
+			return p.createIndex(tokens, i+2, true)
+		}
+		if i < len(tokens) && tokens[i].is("INDEX") {
+			return p.createIndex(tokens, i+1, false)
+		}
+		if i < len(tokens) && tokens[i].is("TYPE") {
+			return p.createType(tokens, i+1)
+		}
+		if i < len(tokens) && tokens[i].is("DOMAIN") {
+			return p.createDomain(tokens, i+1)
+		}
+		if j := viewKeyword(tokens, i); j > 0 {
+			return p.createView(tokens, j+1)
+		}
+	case tokens[0].is("ALTER") && len(tokens) > 1 && tokens[1].is("TABLE"):
+		return p.alterTable(tokens, 2)
+	case tokens[0].is("COMMENT") && len(tokens) > 1 && tokens[1].is("ON"):
+		return p.commentOn(tokens, 2)
+	}
+	return nil
+}
+
+func (p *ddlParser) createTable(tokens []ddlToken, i int) error {
+	if i+2 < len(tokens) && tokens[i].is("IF") && tokens[i+1].is("NOT") && tokens[i+2].is("EXISTS") {
+		i += 3
+	}
+	schema, name, i, err := p.qualifiedName(tokens, i)
+	if err != nil {
+		return err
+	}
+	if i >= len(tokens) || !tokens[i].is("(") {
+		// CREATE TABLE ... AS SELECT, PARTITION OF, LIKE ...
+		return nil
+	}
+	end := matchParen(tokens, i)
+	if end < 0 {
+		return fmt.Errorf("table %s: unbalanced parentheses", name)
+	}
+
+	table := &Table{Name: name, Schema: schema}
+	if existing := p.findTable(schema, name); existing != nil {
+		*existing = *table
+		table = existing
+	} else {
+		p.tables = append(p.tables, table)
+	}
+
+	for _, element := range splitTopLevel(tokens[i+1 : end]) {
+		if err := p.tableElement(table, element); err != nil {
+			return fmt.Errorf("table %s: %v", name, err)
+		}
+	}
+
+	// MySQL table options: ENGINE=InnoDB ... COMMENT='...'
+	for j := end + 1; j < len(tokens); j++ {
+		if tokens[j].is("COMMENT") {
+			if v, ok := optionValue(tokens, j+1); ok {
+				table.Comment = v
+			}
+		}
+	}
+	return nil
+}
+
+// tableElement applies one comma-separated element of CREATE TABLE: a table
+// constraint or a column definition.
+func (p *ddlParser) tableElement(table *Table, tokens []ddlToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	constraint := ""
+	i := 0
+	if tokens[0].is("CONSTRAINT") && len(tokens) > 1 {
+		constraint = p.ident(tokens[1])
+		i = 2
+	}
+	if i >= len(tokens) {
+		return nil
+	}
+	first := tokens[i]
+	if first.kind == tokWord && isAnyWord(first, "PRIMARY", "FOREIGN", "UNIQUE", "CHECK", "EXCLUDE", "KEY", "INDEX", "FULLTEXT", "SPATIAL", "LIKE") {
+		return p.tableConstraint(table, constraint, tokens[i:])
+	}
+	return p.columnDefinition(table, tokens)
+}
+
+func (p *ddlParser) tableConstraint(table *Table, constraint string, tokens []ddlToken) error {
+	switch {
+	case tokens[0].is("PRIMARY"):
+		cols, _, err := p.columnList(tokens, nextParen(tokens, 1))
+		if err != nil {
+			return err
+		}
+		p.setPrimaryKey(table, constraint, cols)
+
+	case tokens[0].is("FOREIGN"):
+		cols, i, err := p.columnList(tokens, nextParen(tokens, 1))
+		if err != nil {
+			return err
+		}
+		if i >= len(tokens) || !tokens[i].is("REFERENCES") {
+			return fmt.Errorf("foreign key without REFERENCES")
+		}
+		refSchema, refTable, i, err := p.qualifiedName(tokens, i+1)
+		if err != nil {
+			return err
+		}
+		var refCols []string
+		if i < len(tokens) && tokens[i].is("(") {
+			if refCols, _, err = p.columnList(tokens, i); err != nil {
+				return err
+			}
+		}
+		if constraint == "" {
+			constraint = p.foreignKeyName(table, cols)
+		}
+		p.addForeignKey(table, ForeignKey{
+			Columns:         cols,
+			ReferencedTable: refTable,
+			ConstraintName:  constraint,
+		}, refSchema, refCols)
+
+	case isAnyWord(tokens[0], "UNIQUE", "KEY", "INDEX"):
+		open := nextParen(tokens, 1)
+		if open < 0 {
+			return fmt.Errorf("index without columns")
+		}
+		cols, ok, err := p.indexColumns(tokens, open)
+		if err != nil || !ok {
+			return err
+		}
+		// MySQL: UNIQUE [KEY|INDEX] [name] (...), KEY name (...)
+		name := constraint
+		for _, t := range tokens[1:open] {
+			if (t.kind == tokWord || t.kind == tokIdent) && !isAnyWord(t, "KEY", "INDEX", "USING", "BTREE", "HASH") {
+				name = p.ident(t)
+				break
+			}
+		}
+		p.addIndex(table, Index{Name: name, Columns: cols, Unique: tokens[0].is("UNIQUE")})
+
+	case tokens[0].is("CHECK"):
+		if expr, ok := p.checkExpression(tokens, 1); ok {
+			applyCheck(table, expr, p.flavor)
+		}
+	}
+	// EXCLUDE, FULLTEXT... are not part of the table model
+	return nil
+}
+
+// checkExpression returns the source of the parenthesized CHECK expression
+// starting at tokens[i].
+func (p *ddlParser) checkExpression(tokens []ddlToken, i int) (string, bool) {
+	if i >= len(tokens) || !tokens[i].is("(") {
+		return "", false
+	}
+	end := matchParen(tokens, i)
+	if end < 0 {
+		return "", false
+	}
+	return p.src[tokens[i].start:tokens[end].end], true
+}
+
+// createType reads CREATE TYPE name AS ENUM ('a', 'b'); other types are
+// skipped.
+func (p *ddlParser) createType(tokens []ddlToken, i int) error {
+	_, name, i, err := p.qualifiedName(tokens, i)
+	if err != nil {
+		return err
+	}
+	if i+2 >= len(tokens) || !tokens[i].is("AS") || !tokens[i+1].is("ENUM") || !tokens[i+2].is("(") {
+		return nil
+	}
+	var values []string
+	for _, t := range tokens[i+3:] {
+		if t.kind == tokString {
+			values = append(values, t.text)
+		}
+	}
+	p.enums[strings.ToLower(name)] = values
+	return nil
+}
+
+// createDomain reads CREATE DOMAIN name [AS] type [constraints]: the base
+// type, its length or precision and the rules of its CHECKs (written on
+// VALUE) are given to the columns declared with the domain.
+func (p *ddlParser) createDomain(tokens []ddlToken, i int) error {
+	_, name, i, err := p.qualifiedName(tokens, i)
+	if err != nil {
+		return err
+	}
+	if i < len(tokens) && tokens[i].is("AS") {
+		i++
+	}
+	if i >= len(tokens) {
+		return nil
+	}
+	// The domain is read as a column named VALUE of a scratch table
+	def := append([]ddlToken{{kind: tokWord, text: "value"}}, tokens[i:]...)
+	scratch := &Table{Name: name}
+	if err := p.columnDefinition(scratch, def); err != nil {
+		return fmt.Errorf("domain %s: %v", name, err)
+	}
+	p.domains[strings.ToLower(name)] = scratch.Columns[0]
+	return nil
+}
+
+// applyDomain gives a column the base type and rules of its domain.
+func applyDomain(col *Column, name string, domain Column) {
+	col.DataType = domain.DataType
+	col.ElementType = domain.ElementType
+	col.MaxLength = domain.MaxLength
+	col.Precision, col.Scale = domain.Precision, domain.Scale
+	col.Enum, col.Min, col.Max, col.Pattern = domain.Enum, domain.Min, domain.Max, domain.Pattern
+	col.Domain = name
+}
+
+// setPrimaryKey sets the primary key of a table and its index.
+func (p *ddlParser) setPrimaryKey(table *Table, constraint string, cols []string) {
+	table.PrimaryKeys = cols
+	for _, c := range cols {
+		if col := findColumn(table, c); col != nil {
+			col.IsNullable = false
+		}
+	}
+
+	if constraint == "" {
+		constraint = table.Name + "_pkey"
+	}
+	if p.flavor == FlavorMySQL {
+		constraint = "PRIMARY"
+	}
+	p.addIndex(table, Index{Name: constraint, Columns: cols, Unique: true, Primary: true})
+}
+
+// addIndex adds an index to a table, replacing one of the same name (or the
+// previous primary key). Unnamed indexes get the name the database would
+// give them.
+func (p *ddlParser) addIndex(table *Table, idx Index) {
+	if idx.Name == "" {
+		if p.flavor == FlavorMySQL {
+			idx.Name = idx.Columns[0]
+			for n := 2; p.hasIndex(table, idx.Name); n++ {
+				idx.Name = fmt.Sprintf("%s_%d", idx.Columns[0], n)
+			}
+		} else {
+			idx.Name = fmt.Sprintf("%s_%s_key", table.Name, strings.Join(idx.Columns, "_"))
+		}
+	}
+
+	for i, existing := range table.Indexes {
+		if existing.Name == idx.Name || (idx.Primary && existing.Primary) {
+			table.Indexes[i] = idx
+			return
+		}
+	}
+	table.Indexes = append(table.Indexes, idx)
+	sort.SliceStable(table.Indexes, func(i, j int) bool { return table.Indexes[i].Name < table.Indexes[j].Name })
+}
+
+func (p *ddlParser) hasIndex(table *Table, name string) bool {
+	for _, idx := range table.Indexes {
+		if idx.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// indexColumns reads the column list of an index at tokens[i]. The second
+// result is false when a part is an expression (a function call or a
+// parenthesized expression); MySQL prefix lengths, col(10), are columns.
+func (p *ddlParser) indexColumns(tokens []ddlToken, i int) ([]string, bool, error) {
+	end := matchParen(tokens, i)
+	if end < 0 {
+		return nil, false, fmt.Errorf("unbalanced parentheses")
+	}
+	for _, item := range splitTopLevel(tokens[i+1 : end]) {
+		if len(item) == 0 || item[0].is("(") {
+			return nil, false, nil
+		}
+		if len(item) > 1 && item[1].is("(") && (p.flavor != FlavorMySQL || len(item) < 3 || item[2].kind != tokNumber) {
+			return nil, false, nil
+		}
+	}
+	cols, _, err := p.columnList(tokens, i)
+	return cols, err == nil, err
+}
+
+// createIndex reads CREATE [UNIQUE] INDEX [CONCURRENTLY] [IF NOT EXISTS] name
+// ON [ONLY] table [USING method] (columns). Partial indexes (WHERE) are left
+// out like the scanners do.
+func (p *ddlParser) createIndex(tokens []ddlToken, i int, unique bool) error {
+	name := ""
+	for ; i < len(tokens) && !tokens[i].is("ON"); i++ {
+		if (tokens[i].kind == tokWord || tokens[i].kind == tokIdent) && !isAnyWord(tokens[i], "CONCURRENTLY", "IF", "NOT", "EXISTS") {
+			name = p.ident(tokens[i])
+		}
+	}
+	if i >= len(tokens) {
+		return nil
+	}
+	i++
+	if i < len(tokens) && tokens[i].is("ONLY") {
+		i++
+	}
+	schema, tableName, i, err := p.qualifiedName(tokens, i)
+	if err != nil {
+		return err
+	}
+	table := p.findTable(schema, tableName)
+	if table == nil {
+		return nil
+	}
+
+	open := nextParen(tokens, i)
+	if open < 0 {
+		return fmt.Errorf("index %s without columns", name)
+	}
+	for _, t := range tokens[open:] {
+		if t.is("WHERE") {
+			return nil
+		}
+	}
+	cols, ok, err := p.indexColumns(tokens, open)
+	if err != nil || !ok {
+		return err
+	}
+	p.addIndex(table, Index{Name: name, Columns: cols, Unique: unique})
+	return nil
+}
+
+func (p *ddlParser) columnDefinition(table *Table, tokens []ddlToken) error {
+	col := Column{Name: p.ident(tokens[0]), IsNullable: true}
+
+	// Data type: words, an argument list and array brackets up to the first
+	// column keyword
+	var typeWords []string
+	var typeArgs []ddlToken
+	isArray := false
+	i := 1
+	for i < len(tokens) {
+		t := tokens[i]
+		if t.kind == tokWord && columnKeywords[strings.ToUpper(t.text)] {
+			break
+		}
+		if t.is("CHARACTER") && len(typeWords) > 0 && i+1 < len(tokens) && tokens[i+1].is("SET") {
+			break
+		}
+		switch {
+		case t.is("("):
+			end := matchParen(tokens, i)
+			if end < 0 {
+				return fmt.Errorf("column %s: unbalanced parentheses", col.Name)
+			}
+			if typeArgs == nil {
+				typeArgs = tokens[i+1 : end]
+			}
+			i = end + 1
+			continue
+		case t.is("["):
+			isArray = true
+		case isAnyWord(t, "UNSIGNED", "SIGNED", "ZEROFILL"):
+			// information_schema.data_type leaves them out
+		case t.kind == tokWord || t.kind == tokIdent:
+			typeWords = append(typeWords, strings.ToLower(t.text))
+		}
+		i++
+	}
+	if len(typeWords) == 0 {
+		return fmt.Errorf("column %s: missing data type", col.Name)
+	}
+
+	dataType, serial := p.normalizeType(typeWords, len(typeArgs) > 0)
+	col.DataType = dataType
+	if isArray && p.flavor == FlavorPostgres {
+		col.DataType = "ARRAY"
+		col.ElementType = dataType
+	}
+	if length, ok := typeLength(dataType, typeArgs); ok && !isArray {
+		col.MaxLength = &length
+	}
+	if precision, scale, ok := p.typePrecision(dataType, typeArgs); ok && !isArray {
+		col.Precision, col.Scale = &precision, &scale
+	}
+	if p.flavor == FlavorMySQL {
+		setEnumArgs(&col, dataType, typeArgs)
+	} else if values, ok := p.enums[typeWords[len(typeWords)-1]]; ok && !isArray {
+		// information_schema reports enum types as USER-DEFINED
+		col.DataType = "USER-DEFINED"
+		col.Enum = values
+	} else if domain, ok := p.domains[typeWords[len(typeWords)-1]]; ok && !isArray {
+		// and domains as their base type, with domain_name
+		applyDomain(&col, typeWords[len(typeWords)-1], domain)
+	}
+	if p.flavor == FlavorMySQL && strings.Join(typeWords, " ") == "serial" {
+		// BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE
+		col.IsIdentity = true
+		col.IsNullable = false
+	}
+	if serial {
+		def := fmt.Sprintf("nextval('%s_%s_seq'::regclass)", table.Name, col.Name)
+		col.DefaultValue = &def
+		col.IsNullable = false
+	}
+
+	// Column constraints and attributes
+	primary, unique := false, false
+	var checks []string
+	for i < len(tokens) {
+		t := tokens[i]
+		switch {
+		case t.is("NOT") && i+1 < len(tokens) && tokens[i+1].is("NULL"):
+			col.IsNullable = false
+			i += 2
+		case t.is("NULL"):
+			i++
+		case t.is("DEFAULT"):
+			start := i + 1
+			i = p.skipExpression(tokens, start)
+			col.DefaultValue = p.defaultValue(tokens[start:i])
+		case t.is("PRIMARY"):
+			primary = true
+			i += 2
+		case t.is("UNIQUE"):
+			unique = true
+			i++
+		case t.is("CHECK"):
+			if expr, ok := p.checkExpression(tokens, i+1); ok {
+				checks = append(checks, expr)
+				i = matchParen(tokens, i+1) + 1
+			} else {
+				i++
+			}
+		case t.is("REFERENCES"):
+			refSchema, refTable, next, err := p.qualifiedName(tokens, i+1)
+			if err != nil {
+				return err
+			}
+			var refCols []string
+			if next < len(tokens) && tokens[next].is("(") {
+				if refCols, next, err = p.columnList(tokens, next); err != nil {
+					return err
+				}
+			}
+			p.addForeignKey(table, ForeignKey{
+				Columns:         []string{col.Name},
+				ReferencedTable: refTable,
+				ConstraintName:  p.foreignKeyName(table, []string{col.Name}),
+			}, refSchema, refCols)
+			i = next
+		case t.is("ON"):
+			i = skipReferentialAction(tokens, i)
+		case t.is("AUTO_INCREMENT"):
+			col.IsIdentity = true
+			i++
+		case t.is("GENERATED") || (t.is("AS") && i+1 < len(tokens) && tokens[i+1].is("(")):
+			// GENERATED {ALWAYS | BY DEFAULT} AS IDENTITY [(options)],
+			// [GENERATED ALWAYS] AS (expr) [STORED | VIRTUAL]
+			for i < len(tokens) && !tokens[i].is("AS") {
+				i++
+			}
+			if i+1 < len(tokens) && tokens[i+1].is("IDENTITY") {
+				col.IsIdentity = true
+				i += 2
+			} else if i+1 < len(tokens) && tokens[i+1].is("(") {
+				col.IsGenerated = true
+				i = matchParen(tokens, i+1) + 1
+				if i == 0 {
+					i = len(tokens)
+				}
+			} else {
+				i++
+			}
+		case t.is("COMMENT"):
+			if v, ok := optionValue(tokens, i+1); ok {
+				col.Comment = v
+			}
+			i += 2
+		case t.is("CONSTRAINT") || t.is("COLLATE"):
+			i += 2
+		case t.is("CHARACTER") && i+1 < len(tokens) && tokens[i+1].is("SET"):
+			i += 3
+		case t.is("("):
+			if end := matchParen(tokens, i); end > 0 {
+				i = end + 1
+			} else {
+				i = len(tokens)
+			}
+		default:
+			i++
+		}
+	}
+
+	if existing := findColumn(table, col.Name); existing != nil {
+		col.Position = existing.Position
+		*existing = col
+	} else {
+		col.Position = len(table.Columns) + 1
+		table.Columns = append(table.Columns, col)
+	}
+	if primary {
+		p.setPrimaryKey(table, "", []string{col.Name})
+	}
+	if unique {
+		p.addIndex(table, Index{Columns: []string{col.Name}, Unique: true})
+	}
+	for _, expr := range checks {
+		applyCheck(table, expr, p.flavor)
+	}
+	return nil
+}
+
+func (p *ddlParser) alterTable(tokens []ddlToken, i int) error {
+	for i < len(tokens) && isAnyWord(tokens[i], "ONLY", "IF", "EXISTS") {
+		i++
+	}
+	schema, name, i, err := p.qualifiedName(tokens, i)
+	if err != nil {
+		return err
+	}
+	table := p.findTable(schema, name)
+	if table == nil {
+		// Tables created elsewhere (or by statements we skip)
+		return nil
+	}
+
+	for _, action := range splitTopLevel(tokens[i:]) {
+		if len(action) < 2 || !action[0].is("ADD") {
+			continue
+		}
+		action = action[1:]
+		switch {
+		case action[0].is("CONSTRAINT"), isAnyWord(action[0], "PRIMARY", "FOREIGN", "UNIQUE", "CHECK", "KEY", "INDEX"):
+			err = p.tableElement(table, action)
+		default:
+			if action[0].is("COLUMN") {
+				action = action[1:]
+			}
+			if len(action) > 3 && action[0].is("IF") && action[1].is("NOT") && action[2].is("EXISTS") {
+				action = action[3:]
+			}
+			err = p.columnDefinition(table, action)
+		}
+		if err != nil {
+			return fmt.Errorf("table %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// commentOn reads COMMENT ON TABLE t IS '...' and COMMENT ON COLUMN t.c IS '...'.
+// Views and materialized views are commented like tables.
+func (p *ddlParser) commentOn(tokens []ddlToken, i int) error {
+	if i+1 < len(tokens) && tokens[i].is("MATERIALIZED") {
+		i++
+	}
+	if i >= len(tokens) {
+		return nil
+	}
+	target := tokens[i]
+	if !target.is("TABLE") && !target.is("VIEW") && !target.is("COLUMN") {
+		return nil
+	}
+
+	var parts []string
+	for i++; i < len(tokens) && !tokens[i].is("IS"); i++ {
+		if tokens[i].kind == tokWord || tokens[i].kind == tokIdent {
+			parts = append(parts, p.ident(tokens[i]))
+		}
+	}
+	if i+1 >= len(tokens) {
+		return fmt.Errorf("COMMENT ON without IS")
+	}
+	comment := ""
+	if tokens[i+1].kind == tokString {
+		comment = tokens[i+1].text
+	}
+
+	column := ""
+	if target.is("COLUMN") {
+		if len(parts) < 2 {
+			return fmt.Errorf("COMMENT ON COLUMN needs table.column")
+		}
+		column, parts = parts[len(parts)-1], parts[:len(parts)-1]
+	}
+	schema, name := "", parts[len(parts)-1]
+	if len(parts) > 1 {
+		schema = parts[len(parts)-2]
+	}
+
+	table := p.findTable(schema, name)
+	if table == nil {
+		return nil
+	}
+	if column == "" {
+		table.Comment = comment
+	} else if col := findColumn(table, column); col != nil {
+		col.Comment = comment
+	}
+	return nil
+}
+
+// addForeignKey appends a foreign key. Without referenced columns the key
+// points to the primary key of the referenced table, resolved at the end.
+func (p *ddlParser) addForeignKey(table *Table, fk ForeignKey, refSchema string, refCols []string) {
+	fk.ReferencedSchema = refSchema
+	if len(refCols) > 0 {
+		fk.ReferencedColumns = refCols
+	} else {
+		p.pending = append(p.pending, pendingReference{table, len(table.ForeignKeys), refSchema})
+	}
+	table.ForeignKeys = append(table.ForeignKeys, fk)
+}
+
+func (p *ddlParser) resolveReferences() {
+	for _, ref := range p.pending {
+		fk := &ref.table.ForeignKeys[ref.index]
+		if target := p.findTable(ref.schema, fk.ReferencedTable); target != nil && len(target.PrimaryKeys) >= len(fk.Columns) {
+			fk.ReferencedColumns = target.PrimaryKeys[:len(fk.Columns)]
+		}
+	}
+}
+
+// foreignKeyName is the name the database gives an unnamed foreign key.
+func (p *ddlParser) foreignKeyName(table *Table, columns []string) string {
+	if p.flavor == FlavorMySQL {
+		prefix := table.Name + "_ibfk_"
+		n := 0
+		for _, fk := range table.ForeignKeys {
+			var k int
+			if strings.HasPrefix(fk.ConstraintName, prefix) {
+				if _, err := fmt.Sscanf(fk.ConstraintName[len(prefix):], "%d", &k); err == nil && k > n {
+					n = k
+				}
+			}
+		}
+		return fmt.Sprintf("%s%d", prefix, n+1)
+	}
+	return fmt.Sprintf("%s_%s_fkey", table.Name, strings.Join(columns, "_"))
+}
+
+func (p *ddlParser) findTable(schema, name string) *Table {
+	for _, t := range p.tables {
+		if !strings.EqualFold(t.Name, name) {
+			continue
+		}
+		if schema == "" || t.Schema == "" || strings.EqualFold(t.Schema, schema) {
+			return t
+		}
+	}
+	return nil
+}
+
+func findColumn(table *Table, name string) *Column {
+	for i := range table.Columns {
+		if strings.EqualFold(table.Columns[i].Name, name) {
+			return &table.Columns[i]
+		}
+	}
+	return nil
+}
+
+// ident returns an identifier as the database stores it: Postgres folds
+// unquoted names to lower case.
+func (p *ddlParser) ident(t ddlToken) string {
+	if t.kind == tokWord && p.flavor == FlavorPostgres {
+		return strings.ToLower(t.text)
+	}
+	return t.text
+}
+
+// qualifiedName reads [schema.]name and returns the index after it.
+func (p *ddlParser) qualifiedName(tokens []ddlToken, i int) (string, string, int, error) {
+	var parts []string
+	for i < len(tokens) && (tokens[i].kind == tokWord || tokens[i].kind == tokIdent) {
+		parts = append(parts, p.ident(tokens[i]))
+		i++
+		if i < len(tokens) && tokens[i].is(".") {
+			i++
+			continue
+		}
+		break
+	}
+	switch len(parts) {
+	case 0:
+		return "", "", i, fmt.Errorf("missing table name")
+	case 1:
+		return "", parts[0], i, nil
+	default:
+		return parts[len(parts)-2], parts[len(parts)-1], i, nil
+	}
+}
+
+// columnList reads "(a, b DESC, c(10))" at tokens[i] and returns the column
+// names and the index after the closing parenthesis.
+func (p *ddlParser) columnList(tokens []ddlToken, i int) ([]string, int, error) {
+	if i < 0 || i >= len(tokens) || !tokens[i].is("(") {
+		return nil, i, fmt.Errorf("expected column list")
+	}
+	end := matchParen(tokens, i)
+	if end < 0 {
+		return nil, i, fmt.Errorf("unbalanced parentheses")
+	}
+	var cols []string
+	for _, item := range splitTopLevel(tokens[i+1 : end]) {
+		if len(item) > 0 {
+			cols = append(cols, p.ident(item[0]))
+		}
+	}
+	if len(cols) == 0 {
+		return nil, i, fmt.Errorf("empty column list")
+	}
+	return cols, end + 1, nil
+}
+
+// defaultValue returns a DEFAULT expression the way column_default shows it:
+// as written, except that DEFAULT NULL has no default and MySQL stores string
+// literals unquoted.
+func (p *ddlParser) defaultValue(tokens []ddlToken) *string {
+	if len(tokens) == 0 || len(tokens) == 1 && tokens[0].is("NULL") {
+		return nil
+	}
+	def := strings.TrimSpace(p.src[tokens[0].start:tokens[len(tokens)-1].end])
+	if p.flavor == FlavorMySQL && len(tokens) == 1 && tokens[0].kind == tokString {
+		def = tokens[0].text
+	}
+	return &def
+}
+
+// skipExpression returns the end of a DEFAULT expression: the first column
+// keyword outside parentheses. The first token always belongs to it (DEFAULT
+// NULL).
+func (p *ddlParser) skipExpression(tokens []ddlToken, i int) int {
+	for j := i; j < len(tokens); j++ {
+		t := tokens[j]
+		if t.is("(") {
+			end := matchParen(tokens, j)
+			if end < 0 {
+				return len(tokens)
+			}
+			j = end
+			continue
+		}
+		if j > i && t.kind == tokWord && columnKeywords[strings.ToUpper(t.text)] {
+			return j
+		}
+		if j > i && t.is("CHARACTER") && j+1 < len(tokens) && tokens[j+1].is("SET") {
+			return j
+		}
+	}
+	return len(tokens)
+}
+
+// skipReferentialAction skips ON DELETE/UPDATE <action> and MySQL's
+// ON UPDATE CURRENT_TIMESTAMP[(n)].
+func skipReferentialAction(tokens []ddlToken, i int) int {
+	i += 2
+	if i < len(tokens) && (tokens[i].is("SET") || tokens[i].is("NO")) {
+		i++
+	}
+	i++
+	if i < len(tokens) && tokens[i].is("(") {
+		if end := matchParen(tokens, i); end > 0 {
+			i = end + 1
+		}
+	}
+	return i
+}
+
+// normalizeType maps the type as written to the information_schema data_type
+// of the flavor. The second result tells a Postgres serial type.
+func (p *ddlParser) normalizeType(words []string, hasArgs bool) (string, bool) {
+	name := strings.Join(words, " ")
+	if p.flavor == FlavorMySQL {
+		switch name {
+		case "integer":
+			return "int", false
+		case "bool", "boolean":
+			return "tinyint", false
+		case "dec", "fixed":
+			return "decimal", false
+		case "double precision", "real":
+			return "double", false
+		case "serial":
+			return "bigint", false
+		}
+		return words[0], false
+	}
+
+	switch name {
+	case "int", "int4", "integer":
+		return "integer", false
+	case "int2", "smallint":
+		return "smallint", false
+	case "int8", "bigint":
+		return "bigint", false
+	case "serial", "serial4":
+		return "integer", true
+	case "bigserial", "serial8":
+		return "bigint", true
+	case "smallserial", "serial2":
+		return "smallint", true
+	case "varchar", "character varying":
+		return "character varying", false
+	case "char", "character", "bpchar":
+		return "character", false
+	case "bool", "boolean":
+		return "boolean", false
+	case "float8", "double precision":
+		return "double precision", false
+	case "float4", "real":
+		return "real", false
+	case "float":
+		if hasArgs {
+			return "real", false
+		}
+		return "double precision", false
+	case "decimal", "numeric":
+		return "numeric", false
+	case "timestamp", "timestamp without time zone":
+		return "timestamp without time zone", false
+	case "timestamptz", "timestamp with time zone":
+		return "timestamp with time zone", false
+	case "time", "time without time zone":
+		return "time without time zone", false
+	case "timetz", "time with time zone":
+		return "time with time zone", false
+	}
+	return name, false
+}
+
+// typeLength returns the declared length of character types, which is what
+// character_maximum_length reports.
+func typeLength(dataType string, args []ddlToken) (int, bool) {
+	switch dataType {
+	case "character varying", "character", "varchar", "char", "nvarchar", "nchar", "varbinary", "binary":
+	default:
+		return 0, false
+	}
+	if len(args) == 0 || args[0].kind != tokNumber {
+		return 0, false
+	}
+	var length int
+	if _, err := fmt.Sscanf(args[0].text, "%d", &length); err != nil {
+		return 0, false
+	}
+	return length, true
+}
+
+// typePrecision returns the declared precision and scale of numeric types,
+// as numeric_precision and numeric_scale report them: MySQL's DECIMAL is
+// DECIMAL(10,0), an unconstrained Postgres numeric has none.
+func (p *ddlParser) typePrecision(dataType string, args []ddlToken) (int, int, bool) {
+	if dataType != "numeric" && dataType != "decimal" {
+		return 0, 0, false
+	}
+	precision, scale := 10, 0
+	if len(args) == 0 {
+		return precision, scale, p.flavor == FlavorMySQL
+	}
+	if args[0].kind != tokNumber {
+		return 0, 0, false
+	}
+	fmt.Sscanf(args[0].text, "%d", &precision)
+	if len(args) > 2 && args[1].is(",") && args[2].kind == tokNumber {
+		fmt.Sscanf(args[2].text, "%d", &scale)
+	}
+	return precision, scale, true
+}
+
+// optionValue reads the value of COMMENT 'x' or COMMENT = 'x'.
+func optionValue(tokens []ddlToken, i int) (string, bool) {
+	if i < len(tokens) && tokens[i].is("=") {
+		i++
+	}
+	if i < len(tokens) && tokens[i].kind == tokString {
+		return tokens[i].text, true
+	}
+	return "", false
+}
+
+// matchParen returns the index of the parenthesis closing tokens[i], or -1.
+func matchParen(tokens []ddlToken, i int) int {
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		switch {
+		case tokens[j].is("("):
+			depth++
+		case tokens[j].is(")"):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// nextParen returns the index of the first "(" from i, or -1.
+func nextParen(tokens []ddlToken, i int) int {
+	for ; i < len(tokens); i++ {
+		if tokens[i].is("(") {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits tokens on the commas outside parentheses.
+func splitTopLevel(tokens []ddlToken) [][]ddlToken {
+	var parts [][]ddlToken
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")"):
+			depth--
+		case t.is(",") && depth == 0:
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+func isAnyWord(t ddlToken, words ...string) bool {
+	for _, w := range words {
+		if t.is(w) {
+			return true
+		}
+	}
+	return false
+}
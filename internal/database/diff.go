@@ -0,0 +1,294 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of SchemaChange.
+const (
+	TableAdded         = "table_added"
+	TableRemoved       = "table_removed"
+	ColumnAdded        = "column_added"
+	ColumnRemoved      = "column_removed"
+	TypeChanged        = "type_changed"
+	NullabilityChanged = "nullability_changed"
+	DefaultChanged     = "default_changed"
+	LengthChanged      = "length_changed"
+	PrimaryKeyChanged  = "primary_key_changed"
+	ChecksChanged      = "checks_changed"
+	ForeignKeyAdded    = "foreign_key_added"
+	ForeignKeyRemoved  = "foreign_key_removed"
+	IndexAdded         = "index_added"
+	IndexRemoved       = "index_removed"
+)
+
+// SchemaChange is a single difference between two versions of a schema.
+type SchemaChange struct {
+	Kind   string `json:"kind"`
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	Column string `json:"column,omitempty"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// String describes the change in a single line, e.g.
+// "public.users.email: type varchar -> text".
+func (c SchemaChange) String() string {
+	target := c.Schema + "." + c.Table
+	if c.Column != "" {
+		target += "." + c.Column
+	}
+
+	switch c.Kind {
+	case TableAdded:
+		return target + ": table added"
+	case TableRemoved:
+		return target + ": table removed"
+	case ColumnAdded:
+		return fmt.Sprintf("%s: column added (%s)", target, c.New)
+	case ColumnRemoved:
+		return fmt.Sprintf("%s: column removed (%s)", target, c.Old)
+	case ForeignKeyAdded:
+		return fmt.Sprintf("%s: foreign key to %s added", target, c.New)
+	case ForeignKeyRemoved:
+		return fmt.Sprintf("%s: foreign key to %s removed", target, c.Old)
+	case IndexAdded:
+		return fmt.Sprintf("%s: index %s added", target, c.New)
+	case IndexRemoved:
+		return fmt.Sprintf("%s: index %s removed", target, c.Old)
+	}
+	return fmt.Sprintf("%s: %s %q -> %q", target, strings.ReplaceAll(strings.TrimSuffix(c.Kind, "_changed"), "_", " "), c.Old, c.New)
+}
+
+// SchemaDiff lists the changes needed to go from an old set of tables (usually
+// the stored metadata) to a new one (usually a fresh scan).
+type SchemaDiff struct {
+	Changes []SchemaChange `json:"changes"`
+}
+
+// IsEmpty reports whether both versions are equivalent.
+func (d SchemaDiff) IsEmpty() bool {
+	return len(d.Changes) == 0
+}
+
+// Count returns the number of changes of the given kind.
+func (d SchemaDiff) Count(kind string) int {
+	n := 0
+	for _, c := range d.Changes {
+		if c.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Tables returns the keys (see TableKey) of the tables affected by at least
+// one change.
+func (d SchemaDiff) Tables() []string {
+	seen := make(map[string]bool)
+	var tables []string
+	for _, c := range d.Changes {
+		if key := TableKey(c.Schema, c.Table); !seen[key] {
+			seen[key] = true
+			tables = append(tables, key)
+		}
+	}
+	return tables
+}
+
+// TableKey identifies a table by lower-case schema and name.
+func TableKey(schema, name string) string {
+	return strings.ToLower(schema + "." + name)
+}
+
+// DiffTables compares two versions of a schema. Tables are matched by schema
+// and name, columns by name, foreign keys by column and target and indexes by
+// columns and uniqueness (their names vary between tools).
+func DiffTables(old, new []Table) SchemaDiff {
+	var diff SchemaDiff
+
+	oldByKey := make(map[string]Table, len(old))
+	for _, t := range old {
+		oldByKey[TableKey(t.Schema, t.Name)] = t
+	}
+	newByKey := make(map[string]Table, len(new))
+	for _, t := range new {
+		newByKey[TableKey(t.Schema, t.Name)] = t
+	}
+
+	for _, t := range new {
+		o, ok := oldByKey[TableKey(t.Schema, t.Name)]
+		if !ok {
+			diff.Changes = append(diff.Changes, SchemaChange{Kind: TableAdded, Schema: t.Schema, Table: t.Name})
+			continue
+		}
+		diff.Changes = append(diff.Changes, diffColumns(o, t)...)
+		diff.Changes = append(diff.Changes, diffForeignKeys(o, t)...)
+		diff.Changes = append(diff.Changes, diffIndexes(o, t)...)
+	}
+	for _, t := range old {
+		if _, ok := newByKey[TableKey(t.Schema, t.Name)]; !ok {
+			diff.Changes = append(diff.Changes, SchemaChange{Kind: TableRemoved, Schema: t.Schema, Table: t.Name})
+		}
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		return TableKey(diff.Changes[i].Schema, diff.Changes[i].Table) < TableKey(diff.Changes[j].Schema, diff.Changes[j].Table)
+	})
+	return diff
+}
+
+func diffColumns(old, new Table) []SchemaChange {
+	var changes []SchemaChange
+	change := func(kind, column, o, n string) {
+		changes = append(changes, SchemaChange{Kind: kind, Schema: new.Schema, Table: new.Name, Column: column, Old: o, New: n})
+	}
+
+	oldCols := make(map[string]Column, len(old.Columns))
+	for _, c := range old.Columns {
+		oldCols[strings.ToLower(c.Name)] = c
+	}
+	newCols := make(map[string]bool, len(new.Columns))
+
+	for _, c := range new.Columns {
+		newCols[strings.ToLower(c.Name)] = true
+		o, ok := oldCols[strings.ToLower(c.Name)]
+		if !ok {
+			change(ColumnAdded, c.Name, "", c.FullType())
+			continue
+		}
+		if !strings.EqualFold(o.FullType(), c.FullType()) {
+			change(TypeChanged, c.Name, o.FullType(), c.FullType())
+		}
+		if o.IsNullable != c.IsNullable {
+			change(NullabilityChanged, c.Name, nullability(o.IsNullable), nullability(c.IsNullable))
+		}
+		if derefString(o.DefaultValue) != derefString(c.DefaultValue) {
+			change(DefaultChanged, c.Name, derefString(o.DefaultValue), derefString(c.DefaultValue))
+		}
+		if derefInt(o.MaxLength) != derefInt(c.MaxLength) {
+			change(LengthChanged, c.Name, derefInt(o.MaxLength), derefInt(c.MaxLength))
+		}
+		if isKeyColumn(o.Name, old.PrimaryKeys) != isKeyColumn(c.Name, new.PrimaryKeys) {
+			change(PrimaryKeyChanged, c.Name, fmt.Sprint(isKeyColumn(o.Name, old.PrimaryKeys)), fmt.Sprint(isKeyColumn(c.Name, new.PrimaryKeys)))
+		}
+		if o.CheckRules() != c.CheckRules() {
+			change(ChecksChanged, c.Name, o.CheckRules(), c.CheckRules())
+		}
+	}
+
+	for _, c := range old.Columns {
+		if !newCols[strings.ToLower(c.Name)] {
+			change(ColumnRemoved, c.Name, c.FullType(), "")
+		}
+	}
+	return changes
+}
+
+// describeForeignKey formats the target of a foreign key: "roles.id", or
+// "orders(id, year)" for a composite key. The table is qualified when it
+// lives in another schema: "security.users(id)".
+func describeForeignKey(schema string, fk ForeignKey) string {
+	target := fk.QualifiedReferencedTable(schema)
+	if len(fk.ReferencedColumns) == 1 && target == fk.ReferencedTable {
+		return target + "." + fk.ReferencedColumns[0]
+	}
+	return target + "(" + strings.Join(fk.ReferencedColumns, ", ") + ")"
+}
+
+func diffForeignKeys(old, new Table) []SchemaChange {
+	var changes []SchemaChange
+
+	key := func(fk ForeignKey) string {
+		return strings.ToLower(strings.Join(fk.Columns, ",") + "->" + describeForeignKey(new.Schema, fk))
+	}
+
+	oldFKs := make(map[string]bool, len(old.ForeignKeys))
+	for _, fk := range old.ForeignKeys {
+		oldFKs[key(fk)] = true
+	}
+	newFKs := make(map[string]bool, len(new.ForeignKeys))
+	for _, fk := range new.ForeignKeys {
+		newFKs[key(fk)] = true
+		if !oldFKs[key(fk)] {
+			changes = append(changes, SchemaChange{Kind: ForeignKeyAdded, Schema: new.Schema, Table: new.Name, Column: strings.Join(fk.Columns, ", "), New: describeForeignKey(new.Schema, fk)})
+		}
+	}
+	for _, fk := range old.ForeignKeys {
+		if !newFKs[key(fk)] {
+			changes = append(changes, SchemaChange{Kind: ForeignKeyRemoved, Schema: new.Schema, Table: new.Name, Column: strings.Join(fk.Columns, ", "), Old: describeForeignKey(old.Schema, fk)})
+		}
+	}
+	return changes
+}
+
+// diffIndexes compares the indexes other than the primary key, which
+// diffColumns already covers.
+func diffIndexes(old, new Table) []SchemaChange {
+	var changes []SchemaChange
+
+	describe := func(idx Index) string {
+		d := "(" + strings.Join(idx.Columns, ", ") + ")"
+		if idx.Unique {
+			d = "unique " + d
+		}
+		return d
+	}
+	key := func(idx Index) string {
+		return strings.ToLower(describe(idx))
+	}
+
+	oldIdx := make(map[string]bool, len(old.Indexes))
+	for _, idx := range old.Indexes {
+		oldIdx[key(idx)] = true
+	}
+	newIdx := make(map[string]bool, len(new.Indexes))
+	for _, idx := range new.Indexes {
+		if idx.Primary {
+			continue
+		}
+		newIdx[key(idx)] = true
+		if !oldIdx[key(idx)] {
+			changes = append(changes, SchemaChange{Kind: IndexAdded, Schema: new.Schema, Table: new.Name, New: describe(idx)})
+		}
+	}
+	for _, idx := range old.Indexes {
+		if !idx.Primary && !newIdx[key(idx)] {
+			changes = append(changes, SchemaChange{Kind: IndexRemoved, Schema: new.Schema, Table: new.Name, Old: describe(idx)})
+		}
+	}
+	return changes
+}
+
+func isKeyColumn(name string, keys []string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func nullability(nullable bool) string {
+	if nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefInt(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return fmt.Sprint(*i)
+}
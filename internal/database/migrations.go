@@ -17,8 +17,12 @@ func InitSchema(db *sql.DB, schema string) error {
 			modeldir varchar(300) NULL,
 			actiondir varchar(300) NULL,
 			testdir varchar(300) NULL,
+			rulepacks varchar(255) NULL,
 			CONSTRAINT xch_tmpprojectres_pkey PRIMARY KEY (projectname)
 		);`, schema),
+		// Validation rule packs selected by the project: "general, latam"
+		fmt.Sprintf(`ALTER TABLE %s.project
+			ADD COLUMN IF NOT EXISTS rulepacks varchar(255) NULL;`, schema),
 		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.dbconn (
 			projectname varchar(50) NOT NULL,
 			connection varchar(30) NOT NULL,
@@ -28,11 +32,14 @@ func InitSchema(db *sql.DB, schema string) error {
 			dbuser varchar(50) NULL,
 			dbpass varchar(100) NULL,
 			dbname varchar(50) NULL,
-			dbschema varchar(50) NULL,
+			dbschema varchar(255) NULL,
 			dbsslmode varchar(10) NULL,
 			dbtimezone varchar(50) NULL default 'UTC',
 			CONSTRAINT xch_dbconn_pkey PRIMARY KEY (projectname, connection)
 		);`, schema),
+		// A connection scans a list of schemas ("sales, security")
+		fmt.Sprintf(`ALTER TABLE %s.dbconn
+			ALTER COLUMN dbschema TYPE varchar(255);`, schema),
 		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.tables (
 			projectname varchar(50) NOT NULL,
 			connection varchar(30) NULL,
@@ -40,8 +47,12 @@ func InitSchema(db *sql.DB, schema string) error {
 			dbschema character varying(50) NOT NULL,
 			tablename character varying(50) NOT NULL,
 			entityname character varying(50),
-			detail character varying(1014) DEFAULT '-'::character varying
+			detail character varying(1014) DEFAULT '-'::character varying,
+			isview boolean DEFAULT false NOT NULL
 		);`, schema),
+		// Views and materialized views are read-only entities
+		fmt.Sprintf(`ALTER TABLE %s.tables
+			ADD COLUMN IF NOT EXISTS isview boolean DEFAULT false NOT NULL;`, schema),
 		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.tablesfields (
 			projectname varchar(50) NOT NULL,
 			connection varchar(30) NULL,
@@ -54,7 +65,7 @@ func InitSchema(db *sql.DB, schema string) error {
 			is_null character varying(3),
 			pk character varying(3),
 			unq character varying(3),
-			ftable character varying(45),
+			ftable character varying(101),
 			fkey character varying(45),
 			label character varying(100),
 			labelhelp character varying(100),
@@ -63,8 +74,62 @@ func InitSchema(db *sql.DB, schema string) error {
 			incrud smallint DEFAULT 1 NOT NULL,
 			val_length character varying(45),
 			auditoria boolean,
-			detail character varying(1024)
+			detail character varying(1024),
+			chk_enum text,
+			chk_min character varying(45),
+			chk_max character varying(45),
+			chk_pattern character varying(1024),
+			chk_length character varying(45),
+			ordinal integer,
+			is_identity character varying(3),
+			is_generated character varying(3),
+			elementtype character varying(100),
+			domainname character varying(100),
+			num_precision integer,
+			num_scale integer,
+			val_min character varying(45),
+			val_max character varying(45),
+			val_pattern character varying(1024),
+			val_enum text,
+			val_message character varying(255)
 		);`, schema),
+		// CHECK/enum rules, for tablesfields created before they existed
+		fmt.Sprintf(`ALTER TABLE %s.tablesfields
+			ADD COLUMN IF NOT EXISTS chk_enum text,
+			ADD COLUMN IF NOT EXISTS chk_min character varying(45),
+			ADD COLUMN IF NOT EXISTS chk_max character varying(45),
+			ADD COLUMN IF NOT EXISTS chk_pattern character varying(1024);`, schema),
+		// Length declared in the database, apart from the val_length edited
+		// in the grid; rows scanned before take it from val_length once
+		fmt.Sprintf(`DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+				WHERE table_schema = '%[1]s' AND table_name = 'tablesfields' AND column_name = 'chk_length') THEN
+				ALTER TABLE %[1]s.tablesfields ADD COLUMN chk_length character varying(45);
+				UPDATE %[1]s.tablesfields SET chk_length = val_length WHERE val_length <> '';
+			END IF;
+		END $$;`, schema),
+		// Column position and identity/generated flags
+		fmt.Sprintf(`ALTER TABLE %s.tablesfields
+			ADD COLUMN IF NOT EXISTS ordinal integer,
+			ADD COLUMN IF NOT EXISTS is_identity character varying(3),
+			ADD COLUMN IF NOT EXISTS is_generated character varying(3);`, schema),
+		// Foreign keys to another schema keep it: "security.users"
+		fmt.Sprintf(`ALTER TABLE %s.tablesfields
+			ALTER COLUMN ftable TYPE character varying(101);`, schema),
+		// Array element type, domain and numeric precision/scale
+		fmt.Sprintf(`ALTER TABLE %s.tablesfields
+			ADD COLUMN IF NOT EXISTS elementtype character varying(100),
+			ADD COLUMN IF NOT EXISTS domainname character varying(100),
+			ADD COLUMN IF NOT EXISTS num_precision integer,
+			ADD COLUMN IF NOT EXISTS num_scale integer;`, schema),
+		// Validation overrides edited in the fields grid
+		fmt.Sprintf(`ALTER TABLE %s.tablesfields
+			ADD COLUMN IF NOT EXISTS val_min character varying(45),
+			ADD COLUMN IF NOT EXISTS val_max character varying(45),
+			ADD COLUMN IF NOT EXISTS val_pattern character varying(1024),
+			ADD COLUMN IF NOT EXISTS val_enum text,
+			ADD COLUMN IF NOT EXISTS val_message character varying(255);`, schema),
 		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.tablesrels (
 			"connection" varchar(30) NULL,
 			dbname varchar(50) NULL,
@@ -74,7 +139,28 @@ func InitSchema(db *sql.DB, schema string) error {
 			fname varchar(100) NOT NULL,
 			tabler varchar(50) NOT NULL,
 			fnamepk varchar(100) NOT NULL,
-			is_null int4 DEFAULT 1 NULL
+			is_null int4 DEFAULT 1 NULL,
+			constraintname varchar(100) NULL,
+			position int4 DEFAULT 1 NOT NULL,
+			schemar varchar(50) NULL
+		);`, schema),
+		// Composite foreign keys: one row per column, grouped by constraint
+		fmt.Sprintf(`ALTER TABLE %s.tablesrels
+			ADD COLUMN IF NOT EXISTS constraintname varchar(100) NULL,
+			ADD COLUMN IF NOT EXISTS position int4 DEFAULT 1 NOT NULL;`, schema),
+		// Schema of the referenced table (tabler)
+		fmt.Sprintf(`ALTER TABLE %s.tablesrels
+			ADD COLUMN IF NOT EXISTS schemar varchar(50) NULL;`, schema),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.tablesindexes (
+			projectname varchar(50) NOT NULL,
+			connection  varchar(30) NOT NULL,
+			dbschema    varchar(50) NOT NULL,
+			tablename   varchar(50) NOT NULL,
+			indexname   varchar(100) NOT NULL,
+			columns     varchar(1024) NOT NULL,
+			isunique    boolean NOT NULL DEFAULT false,
+			isprimary   boolean NOT NULL DEFAULT false,
+			CONSTRAINT tablesindexes_pkey PRIMARY KEY (projectname, connection, dbschema, tablename, indexname)
 		);`, schema),
 		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.file_templates (
 			id         serial PRIMARY KEY,
@@ -96,6 +182,38 @@ func InitSchema(db *sql.DB, schema string) error {
 			details varchar(1024) NULL,
 			CONSTRAINT subsystem_pkey PRIMARY KEY (projectname, subsystem)
 		);`, schema),
+		// Type mapping rules of a project: database type pattern -> spec type
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.typemaps (
+			id          serial PRIMARY KEY,
+			projectname varchar(50) NOT NULL,
+			dbtype      varchar(200) NOT NULL,
+			spectype    varchar(50) NOT NULL,
+			format      varchar(50) NULL,
+			validation  text NULL,
+			orderlist   integer DEFAULT 0
+		);`, schema),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.generation (
+			id           serial PRIMARY KEY,
+			projectname  varchar(50) NOT NULL,
+			connection   varchar(30) NOT NULL,
+			subsystem    varchar(15) NULL,
+			tables       text NULL,
+			templates    text NULL,
+			generatedby  varchar(100) NULL,
+			generatedat  timestamp NOT NULL DEFAULT now(),
+			rolledbackat timestamp NULL
+		);`, schema),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.generation_files (
+			generation_id integer NOT NULL REFERENCES %s.generation(id) ON DELETE CASCADE,
+			filepath      varchar(1024) NOT NULL,
+			backuppath    varchar(1024) NULL,
+			status        varchar(10) NULL,
+			prevpristine  text NULL,
+			CONSTRAINT generation_files_pkey PRIMARY KEY (generation_id, filepath)
+		);`, schema, schema),
+		// Pristine copy a run replaced, put back when the run is rolled back
+		fmt.Sprintf(`ALTER TABLE %s.generation_files
+			ADD COLUMN IF NOT EXISTS prevpristine text NULL;`, schema),
 		// Seed default file_templates (idempotent)
 		fmt.Sprintf(`INSERT INTO %s.file_templates (version,grouptype,category,name,path,file,template,source,orderlist,visible,typefile) VALUES
 			('api-loader','crud','list-entity',   'List',         '[rootprj]/[entity]/','[entity]_list.yaml',         'templatesgen/entidad_list.tpl',         '',1,1,'M'),
@@ -1,477 +1,187 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
-
-	_ "github.com/go-sql-driver/mysql"
-	_ "github.com/lib/pq"
+	"sync"
 )
 
-type Scanner struct {
-	db     *sql.DB
-	driver string
-}
-
-func NewScanner() *Scanner {
-	return &Scanner{}
+// Dialect describes a database the scanner can introspect. Every dialect
+// registers itself from an init function in its own scanner_<name>.go file.
+type Dialect struct {
+	Name    string   // dbtype stored in the connections
+	Label   string   // shown in the connection form
+	Aliases []string // other accepted dbtype values
+	New     func() DatabaseScanner
 }
 
-func (s *Scanner) Connect(config *DatabaseConfig) error {
-	var dsn string
-
-	switch config.Driver {
-	case "postgres":
-		dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
-			config.Host, config.Port, config.Username, config.Password,
-			config.Database, config.SSLMode, config.Timezone)
-		s.driver = "postgres"
-
-	case "mysql":
-		dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=%s",
-			config.Username, config.Password, config.Host, config.Port,
-			config.Database, config.Timezone)
-		s.driver = "mysql"
+var (
+	dialects []Dialect
+	byName   = make(map[string]int)
+)
 
-	default:
-		return fmt.Errorf("unsupported database driver: %s", config.Driver)
+// Register makes a dialect available to NewScanner. It panics when the name
+// or one of the aliases is already taken, like database/sql does.
+func Register(d Dialect) {
+	if d.Name == "" || d.New == nil {
+		panic("database: Register needs a name and a constructor")
 	}
-
-	db, err := sql.Open(config.Driver, dsn)
-	if err != nil {
-		return fmt.Errorf("error connecting to database: %v", err)
-	}
-
-	if err := db.Ping(); err != nil {
-		return fmt.Errorf("error pinging database: %v", err)
+	for _, name := range append([]string{d.Name}, d.Aliases...) {
+		key := strings.ToLower(name)
+		if _, dup := byName[key]; dup {
+			panic("database: Register called twice for dialect " + name)
+		}
+		byName[key] = len(dialects)
 	}
-
-	s.db = db
-	return nil
+	dialects = append(dialects, d)
 }
 
-func (s *Scanner) Disconnect() {
-	if s.db != nil {
-		s.db.Close()
-	}
+// Dialects returns the registered dialects in registration order.
+func Dialects() []Dialect {
+	return append([]Dialect(nil), dialects...)
 }
 
-func (s *Scanner) GetTables(schema string, tableFilter []string) ([]Table, error) {
-	switch s.driver {
-	case "postgres":
-		return s.getPostgresTables(schema, tableFilter)
-	case "mysql":
-		return s.getMySQLTables(schema, tableFilter)
-	default:
-		return nil, fmt.Errorf("unsupported database driver: %s", s.driver)
+// LookupDialect finds a dialect by name or alias.
+func LookupDialect(dbtype string) (Dialect, bool) {
+	i, ok := byName[strings.ToLower(strings.TrimSpace(dbtype))]
+	if !ok {
+		return Dialect{}, false
 	}
+	return dialects[i], true
 }
 
-func (s *Scanner) getPostgresTables(schema string, tableFilter []string) ([]Table, error) {
-	var tables []Table
-
-	// Obtener todas las tablas
-	query := `
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = $1 
-		AND table_type = 'BASE TABLE'
-		ORDER BY table_name
-	`
-
-	rows, err := s.db.Query(query, schema)
-	if err != nil {
-		return nil, fmt.Errorf("error querying tables: %v", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			return nil, err
-		}
-
-		// Filtrar si es necesario
-		if len(tableFilter) > 0 && tableFilter[0] != "*" {
-			found := false
-			for _, filter := range tableFilter {
-				if strings.EqualFold(filter, tableName) {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-
-		// Obtener columnas
-		columns, err := s.getPostgresColumns(schema, tableName)
-		if err != nil {
-			return nil, err
-		}
-
-		// Obtener claves primarias
-		primaryKeys, err := s.getPostgresPrimaryKeys(schema, tableName)
-		if err != nil {
-			return nil, err
-		}
-
-		// Obtener claves foráneas
-		foreignKeys, err := s.getPostgresForeignKeys(schema, tableName)
-		if err != nil {
-			return nil, err
-		}
-
-		table := Table{
-			Name:        tableName,
-			Schema:      schema,
-			Columns:     columns,
-			PrimaryKeys: primaryKeys,
-			ForeignKeys: foreignKeys,
-		}
-
-		tables = append(tables, table)
+// NewScanner returns a scanner for a connection dbtype.
+func NewScanner(dbtype string) (DatabaseScanner, error) {
+	d, ok := LookupDialect(dbtype)
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver: %s", dbtype)
 	}
-
-	return tables, nil
+	return d.New(), nil
 }
 
-func (s *Scanner) getMySQLTables(schema string, tableFilter []string) ([]Table, error) {
-	var tables []Table
-
-	query := `
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = ?
-		AND table_type = 'BASE TABLE'
-		ORDER BY table_name
-	`
-
-	rows, err := s.db.Query(query, schema)
+// openDB opens and pings a database/sql connection.
+func openDB(ctx context.Context, driverName, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
-		return nil, fmt.Errorf("error querying tables: %v", err)
+		return nil, fmt.Errorf("error connecting to database: %v", err)
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			return nil, err
-		}
-
-		// Filtrar si es necesario
-		if len(tableFilter) > 0 && tableFilter[0] != "*" {
-			found := false
-			for _, filter := range tableFilter {
-				if strings.EqualFold(filter, tableName) {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-
-		// Obtener columnas
-		columns, err := s.getMySQLColumns(schema, tableName)
-		if err != nil {
-			return nil, err
-		}
-
-		// Obtener claves primarias
-		primaryKeys, err := s.getMySQLPrimaryKeys(schema, tableName)
-		if err != nil {
-			return nil, err
-		}
-
-		// Obtener claves foráneas
-		foreignKeys, err := s.getMySQLForeignKeys(schema, tableName)
-		if err != nil {
-			return nil, err
-		}
 
-		table := Table{
-			Name:        tableName,
-			Schema:      schema,
-			Columns:     columns,
-			PrimaryKeys: primaryKeys,
-			ForeignKeys: foreignKeys,
-		}
-
-		tables = append(tables, table)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error pinging database: %v", err)
 	}
 
-	return tables, nil
+	return db, nil
 }
 
-func (s *Scanner) getPostgresColumns(schema, tableName string) ([]Column, error) {
-	query := `
-		SELECT 
-			c.column_name,
-			c.data_type,
-			c.is_nullable,
-			c.column_default,
-			c.character_maximum_length,
-			pgd.description as column_comment
-		FROM information_schema.columns c
-		LEFT JOIN pg_catalog.pg_statio_all_tables st 
-			ON c.table_schema = st.schemaname AND c.table_name = st.relname
-		LEFT JOIN pg_catalog.pg_description pgd 
-			ON pgd.objoid = st.relid AND pgd.objsubid = c.ordinal_position
-		WHERE c.table_schema = $1 
-			AND c.table_name = $2
-		ORDER BY c.ordinal_position
-	`
-
-	rows, err := s.db.Query(query, schema, tableName)
-	if err != nil {
-		return nil, err
+// closeDB closes the connection of a scanner, if any.
+func closeDB(db *sql.DB) {
+	if db != nil {
+		db.Close()
 	}
-	defer rows.Close()
-
-	var columns []Column
-	for rows.Next() {
-		var col Column
-		var isNullable string
-		var defaultValue, maxLength, comment sql.NullString
-
-		if err := rows.Scan(
-			&col.Name,
-			&col.DataType,
-			&isNullable,
-			&defaultValue,
-			&maxLength,
-			&comment,
-		); err != nil {
-			return nil, err
-		}
-
-		col.IsNullable = (isNullable == "YES")
-
-		if defaultValue.Valid {
-			val := defaultValue.String
-			col.DefaultValue = &val
-		}
-
-		if maxLength.Valid && maxLength.String != "" {
-			var length int
-			fmt.Sscanf(maxLength.String, "%d", &length)
-			col.MaxLength = &length
-		}
-
-		if comment.Valid {
-			col.Comment = comment.String
-		}
-
-		columns = append(columns, col)
-	}
-
-	return columns, nil
 }
 
-func (s *Scanner) getMySQLColumns(schema, tableName string) ([]Column, error) {
-	query := `
-		SELECT 
-			column_name,
-			data_type,
-			is_nullable,
-			column_default,
-			character_maximum_length,
-			column_comment
-		FROM information_schema.columns
-		WHERE table_schema = ?
-			AND table_name = ?
-		ORDER BY ordinal_position
-	`
-
-	rows, err := s.db.Query(query, schema, tableName)
-	if err != nil {
-		return nil, err
+// tableSelected applies the table filter of GetTables: empty or "*" selects
+// every table, otherwise names are matched case-insensitively.
+func tableSelected(tableFilter []string, tableName string) bool {
+	if len(tableFilter) == 0 || tableFilter[0] == "*" {
+		return true
 	}
-	defer rows.Close()
-
-	var columns []Column
-	for rows.Next() {
-		var col Column
-		var isNullable string
-		var defaultValue, maxLength sql.NullString
-		var comment string
-
-		if err := rows.Scan(
-			&col.Name,
-			&col.DataType,
-			&isNullable,
-			&defaultValue,
-			&maxLength,
-			&comment,
-		); err != nil {
-			return nil, err
+	for _, filter := range tableFilter {
+		if strings.EqualFold(filter, tableName) {
+			return true
 		}
-
-		col.IsNullable = (isNullable == "YES")
-
-		if defaultValue.Valid {
-			val := defaultValue.String
-			col.DefaultValue = &val
-		}
-
-		if maxLength.Valid && maxLength.String != "" {
-			var length int
-			fmt.Sscanf(maxLength.String, "%d", &length)
-			col.MaxLength = &length
-		}
-
-		col.Comment = comment
-
-		columns = append(columns, col)
 	}
-
-	return columns, nil
+	return false
 }
 
-func (s *Scanner) getPostgresPrimaryKeys(schema, tableName string) ([]string, error) {
-	query := `
-		SELECT a.attname
-		FROM pg_index i
-		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
-		WHERE i.indrelid = $1::regclass
-			AND i.indisprimary
-	`
-
-	rows, err := s.db.Query(query, fmt.Sprintf("%s.%s", schema, tableName))
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var primaryKeys []string
-	for rows.Next() {
-		var columnName string
-		if err := rows.Scan(&columnName); err != nil {
-			return nil, err
+// SplitSchemas reads the schema list of a connection: names separated by
+// commas or spaces, duplicates dropped. An empty list is "public".
+func SplitSchemas(list string) []string {
+	var schemas []string
+	seen := make(map[string]bool)
+	for _, name := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			schemas = append(schemas, name)
 		}
-		primaryKeys = append(primaryKeys, columnName)
-	}
-
-	return primaryKeys, nil
-}
-
-func (s *Scanner) getMySQLPrimaryKeys(schema, tableName string) ([]string, error) {
-	query := `
-		SELECT column_name
-		FROM information_schema.key_column_usage
-		WHERE constraint_name = 'PRIMARY'
-			AND table_schema = ?
-			AND table_name = ?
-		ORDER BY ordinal_position
-	`
-
-	rows, err := s.db.Query(query, schema, tableName)
-	if err != nil {
-		return nil, err
 	}
-	defer rows.Close()
-
-	var primaryKeys []string
-	for rows.Next() {
-		var columnName string
-		if err := rows.Scan(&columnName); err != nil {
-			return nil, err
-		}
-		primaryKeys = append(primaryKeys, columnName)
+	if len(schemas) == 0 {
+		return []string{"public"}
 	}
-
-	return primaryKeys, nil
+	return schemas
 }
 
-func (s *Scanner) GetForeignKeys(schema, tableName string) ([]ForeignKey, error) {
-	switch s.driver {
-	case "postgres":
-		return s.getPostgresForeignKeys(schema, tableName)
-	case "mysql":
-		return s.getMySQLForeignKeys(schema, tableName)
-	default:
-		return nil, fmt.Errorf("unsupported database driver: %s", s.driver)
+// GetTablesIn scans the tables of several schemas, in the order given.
+// Foreign keys between them keep the schema of the referenced table.
+func GetTablesIn(ctx context.Context, scanner DatabaseScanner, schemas []string, tableFilter []string) ([]Table, error) {
+	var tables []Table
+	for _, schema := range schemas {
+		found, err := scanner.GetTables(ctx, schema, tableFilter)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %v", schema, err)
+		}
+		tables = append(tables, found...)
 	}
+	return tables, nil
 }
 
-func (s *Scanner) getPostgresForeignKeys(schema, tableName string) ([]ForeignKey, error) {
-	query := `
-		SELECT
-			kcu.column_name,
-			ccu.table_name AS referenced_table,
-			ccu.column_name AS referenced_column,
-			tc.constraint_name
-		FROM information_schema.table_constraints tc
-		JOIN information_schema.key_column_usage kcu
-			ON tc.constraint_name = kcu.constraint_name
-			AND tc.table_schema = kcu.table_schema
-		JOIN information_schema.constraint_column_usage ccu
-			ON ccu.constraint_name = tc.constraint_name
-			AND ccu.table_schema = tc.table_schema
-		WHERE tc.constraint_type = 'FOREIGN KEY'
-			AND tc.table_schema = $1
-			AND tc.table_name = $2
-	`
-
-	rows, err := s.db.Query(query, schema, tableName)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// DefaultScanWorkers is the number of tables a scanner introspects at the
+// same time when DatabaseConfig.Workers is not set.
+const DefaultScanWorkers = 8
 
-	var foreignKeys []ForeignKey
-	for rows.Next() {
-		var fk ForeignKey
-		if err := rows.Scan(
-			&fk.ColumnName,
-			&fk.ReferencedTable,
-			&fk.ReferencedColumn,
-			&fk.ConstraintName,
-		); err != nil {
-			return nil, err
-		}
-		foreignKeys = append(foreignKeys, fk)
+func (c *DatabaseConfig) workers() int {
+	if c.Workers > 0 {
+		return c.Workers
 	}
-
-	return foreignKeys, nil
+	return DefaultScanWorkers
 }
 
-func (s *Scanner) getMySQLForeignKeys(schema, tableName string) ([]ForeignKey, error) {
-	query := `
-		SELECT
-			column_name,
-			referenced_table_name,
-			referenced_column_name,
-			constraint_name
-		FROM information_schema.key_column_usage
-		WHERE constraint_schema = ?
-			AND table_name = ?
-			AND referenced_table_name IS NOT NULL
-	`
-
-	rows, err := s.db.Query(query, schema, tableName)
-	if err != nil {
-		return nil, err
+// scanTables fills the tables listed by GetTables (name, schema and the
+// facts of the listing query) with at most workers of them at a time. The
+// first error, or the cancellation of ctx, stops the tables not started yet;
+// the ones running see ctx cancelled and return.
+func scanTables(parent context.Context, workers int, tables []Table, scan func(ctx context.Context, table *Table) error) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	jobs := make(chan int)
+	for w := 0; w < workers && w < len(tables); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := scan(ctx, &tables[i]); err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("table %s: %v", tables[i].Name, err)
+						cancel()
+					})
+				}
+			}
+		}()
 	}
-	defer rows.Close()
 
-	var foreignKeys []ForeignKey
-	for rows.Next() {
-		var fk ForeignKey
-		if err := rows.Scan(
-			&fk.ColumnName,
-			&fk.ReferencedTable,
-			&fk.ReferencedColumn,
-			&fk.ConstraintName,
-		); err != nil {
-			return nil, err
+feed:
+	for i := range tables {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
 		}
-		foreignKeys = append(foreignKeys, fk)
 	}
+	close(jobs)
+	wg.Wait()
 
-	return foreignKeys, nil
+	// A cancelled scan reports why rather than the queries it interrupted
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	return firstErr
 }
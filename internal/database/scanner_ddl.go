@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	Register(Dialect{
+		Name:  "ddl",
+		Label: "DDL file",
+		New:   func() DatabaseScanner { return &ddlScanner{} },
+	})
+}
+
+// ddlScanner reads the tables from a schema script instead of a live
+// database, so a connection can point to a migration dump. config.Database
+// is the path of the file; the flavor is detected from its content. The tables
+// are kept with their schema resolved against config.Schemas.
+type ddlScanner struct {
+	tables []Table
+}
+
+func (s *ddlScanner) Connect(ctx context.Context, config *DatabaseConfig) error {
+	if config.Database == "" {
+		return fmt.Errorf("ddl requires the path of the schema file")
+	}
+	src, err := os.ReadFile(config.Database)
+	if err != nil {
+		return fmt.Errorf("error reading schema file: %v", err)
+	}
+	tables, err := ParseDDL(string(src), "")
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %v", config.Database, err)
+	}
+	schemas := config.Schemas
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+	s.tables = TablesInSchemas(tables, schemas)
+	return nil
+}
+
+func (s *ddlScanner) Disconnect() {
+	s.tables = nil
+}
+
+// GetTables only reads the parsed script, so ctx is checked once.
+func (s *ddlScanner) GetTables(ctx context.Context, schema string, tableFilter []string) ([]Table, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var tables []Table
+	for _, t := range s.tables {
+		if strings.EqualFold(t.Schema, schema) && tableSelected(tableFilter, t.Name) {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+func (s *ddlScanner) GetForeignKeys(ctx context.Context, schema, tableName string) ([]ForeignKey, error) {
+	for _, t := range s.tables {
+		if strings.EqualFold(t.Schema, schema) && t.Name == tableName {
+			return t.ForeignKeys, nil
+		}
+	}
+	return nil, nil
+}
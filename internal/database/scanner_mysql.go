@@ -0,0 +1,351 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	Register(Dialect{
+		Name:    "mysql",
+		Label:   "MySQL",
+		Aliases: []string{"mariadb"},
+		New:     func() DatabaseScanner { return &mysqlScanner{} },
+	})
+}
+
+type mysqlScanner struct {
+	db      *sql.DB
+	workers int
+}
+
+func (s *mysqlScanner) Connect(ctx context.Context, config *DatabaseConfig) error {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=%s",
+		config.Username, config.Password, config.Host, config.Port,
+		config.Database, config.Timezone)
+
+	db, err := openDB(ctx, "mysql", dsn)
+	if err != nil {
+		return err
+	}
+	s.db = db
+	s.workers = config.workers()
+	return nil
+}
+
+func (s *mysqlScanner) Disconnect() {
+	closeDB(s.db)
+}
+
+// GetTables lists the tables and views of the schema and introspects them
+// with a pool of workers, one table per worker at a time.
+func (s *mysqlScanner) GetTables(ctx context.Context, schema string, tableFilter []string) ([]Table, error) {
+	var tables []Table
+
+	query := `
+		SELECT table_name, table_comment, table_type
+		FROM information_schema.tables 
+		WHERE table_schema = ?
+		AND table_type IN ('BASE TABLE', 'VIEW')
+		ORDER BY table_name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, comment, tableType string
+		if err := rows.Scan(&tableName, &comment, &tableType); err != nil {
+			return nil, err
+		}
+		isView := tableType == "VIEW"
+		if isView {
+			// table_comment of a view is always "VIEW"
+			comment = ""
+		}
+
+		// Filtrar si es necesario
+		if !tableSelected(tableFilter, tableName) {
+			continue
+		}
+
+		tables = append(tables, Table{
+			Name:    tableName,
+			Schema:  schema,
+			Comment: comment,
+			IsView:  isView,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	err = scanTables(ctx, s.workers, tables, func(ctx context.Context, table *Table) error {
+		var err error
+
+		// Obtener columnas
+		if table.Columns, err = s.getColumns(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Obtener claves primarias
+		if table.PrimaryKeys, err = s.getPrimaryKeys(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Obtener claves foráneas
+		if table.ForeignKeys, err = s.GetForeignKeys(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Obtener índices y restricciones únicas
+		if table.Indexes, err = s.getIndexes(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Restricciones CHECK
+		return s.applyChecks(ctx, table)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+func (s *mysqlScanner) getColumns(ctx context.Context, schema, tableName string) ([]Column, error) {
+	query := `
+		SELECT 
+			column_name,
+			data_type,
+			is_nullable,
+			column_default,
+			character_maximum_length,
+			column_comment,
+			column_type,
+			ordinal_position,
+			extra,
+			CASE WHEN data_type = 'decimal' THEN numeric_precision END,
+			CASE WHEN data_type = 'decimal' THEN numeric_scale END
+		FROM information_schema.columns
+		WHERE table_schema = ?
+			AND table_name = ?
+		ORDER BY ordinal_position
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var col Column
+		var isNullable string
+		var defaultValue, maxLength sql.NullString
+		var comment, columnType, extra string
+		var precision, scale sql.NullInt64
+
+		if err := rows.Scan(
+			&col.Name,
+			&col.DataType,
+			&isNullable,
+			&defaultValue,
+			&maxLength,
+			&comment,
+			&columnType,
+			&col.Position,
+			&extra,
+			&precision,
+			&scale,
+		); err != nil {
+			return nil, err
+		}
+
+		// enum('a','b') / set('a','b')
+		applyEnumType(&col, columnType)
+
+		col.IsNullable = (isNullable == "YES")
+
+		if defaultValue.Valid {
+			val := defaultValue.String
+			col.DefaultValue = &val
+		}
+
+		if maxLength.Valid && maxLength.String != "" {
+			var length int
+			fmt.Sscanf(maxLength.String, "%d", &length)
+			col.MaxLength = &length
+		}
+
+		col.Comment = comment
+
+		if precision.Valid && scale.Valid {
+			p, sc := int(precision.Int64), int(scale.Int64)
+			col.Precision, col.Scale = &p, &sc
+		}
+
+		// extra: "auto_increment", "VIRTUAL GENERATED", "STORED GENERATED"
+		// ("DEFAULT_GENERATED" is only a default expression)
+		extra = strings.ToUpper(extra)
+		col.IsIdentity = strings.Contains(extra, "AUTO_INCREMENT")
+		col.IsGenerated = strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED")
+
+		columns = append(columns, col)
+	}
+
+	return columns, rows.Err()
+}
+
+// applyChecks reads the CHECK constraints of the table into the rules of its
+// columns. Servers before MySQL 8.0.16 have no check_constraints table (and
+// ignore CHECK), so there is nothing to read.
+func (s *mysqlScanner) applyChecks(ctx context.Context, table *Table) error {
+	query := `
+		SELECT cc.check_clause
+		FROM information_schema.check_constraints cc
+		JOIN information_schema.table_constraints tc
+			ON tc.constraint_schema = cc.constraint_schema
+			AND tc.constraint_name = cc.constraint_name
+		WHERE tc.constraint_type = 'CHECK'
+			AND tc.table_schema = ?
+			AND tc.table_name = ?
+		ORDER BY cc.constraint_name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, table.Schema, table.Name)
+	if err != nil {
+		var myErr *mysql.MySQLError
+		if errors.As(err, &myErr) && myErr.Number == 1109 {
+			return nil
+		}
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var clause string
+		if err := rows.Scan(&clause); err != nil {
+			return err
+		}
+		// The clause keeps the quotes of string literals escaped
+		applyCheck(table, strings.ReplaceAll(clause, `\'`, "'"), FlavorMySQL)
+	}
+
+	return rows.Err()
+}
+
+func (s *mysqlScanner) getPrimaryKeys(ctx context.Context, schema, tableName string) ([]string, error) {
+	query := `
+		SELECT column_name
+		FROM information_schema.key_column_usage
+		WHERE constraint_name = 'PRIMARY'
+			AND table_schema = ?
+			AND table_name = ?
+		ORDER BY ordinal_position
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var primaryKeys []string
+	for rows.Next() {
+		var columnName string
+		if err := rows.Scan(&columnName); err != nil {
+			return nil, err
+		}
+		primaryKeys = append(primaryKeys, columnName)
+	}
+
+	return primaryKeys, rows.Err()
+}
+
+func (s *mysqlScanner) GetForeignKeys(ctx context.Context, schema, tableName string) ([]ForeignKey, error) {
+	query := `
+		SELECT
+			constraint_name,
+			referenced_table_schema,
+			referenced_table_name,
+			column_name,
+			referenced_column_name
+		FROM information_schema.key_column_usage
+		WHERE constraint_schema = ?
+			AND table_name = ?
+			AND referenced_table_name IS NOT NULL
+		ORDER BY constraint_name, ordinal_position
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var name, refSchema, refTable, column, refColumn string
+		if err := rows.Scan(&name, &refSchema, &refTable, &column, &refColumn); err != nil {
+			return nil, err
+		}
+		foreignKeys = appendForeignKeyColumn(foreignKeys, name, refSchema, refTable, column, refColumn)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+// getIndexes reads information_schema.statistics. Functional key parts have
+// no column and leave their index out.
+func (s *mysqlScanner) getIndexes(ctx context.Context, schema, tableName string) ([]Index, error) {
+	query := `
+		SELECT index_name, non_unique, column_name
+		FROM information_schema.statistics
+		WHERE table_schema = ?
+			AND table_name = ?
+		ORDER BY index_name, seq_in_index
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	functional := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var nonUnique int
+		var column sql.NullString
+		if err := rows.Scan(&name, &nonUnique, &column); err != nil {
+			return nil, err
+		}
+		if !column.Valid {
+			functional[name] = true
+		}
+		indexes = appendIndexColumn(indexes, name, nonUnique == 0, name == "PRIMARY", column.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []Index
+	for _, idx := range indexes {
+		if !functional[idx.Name] {
+			out = append(out, idx)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,442 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+func init() {
+	Register(Dialect{
+		Name:    "postgres",
+		Label:   "Postgres",
+		Aliases: []string{"postgresql"},
+		New:     func() DatabaseScanner { return &postgresScanner{} },
+	})
+}
+
+type postgresScanner struct {
+	db      *sql.DB
+	workers int
+}
+
+func (s *postgresScanner) Connect(ctx context.Context, config *DatabaseConfig) error {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
+		config.Host, config.Port, config.Username, config.Password,
+		config.Database, config.SSLMode, config.Timezone)
+
+	db, err := openDB(ctx, "postgres", dsn)
+	if err != nil {
+		return err
+	}
+	s.db = db
+	s.workers = config.workers()
+	return nil
+}
+
+func (s *postgresScanner) Disconnect() {
+	closeDB(s.db)
+}
+
+// GetTables lists the tables and views of the schema and introspects them
+// with a pool of workers, one table per worker at a time.
+func (s *postgresScanner) GetTables(ctx context.Context, schema string, tableFilter []string) ([]Table, error) {
+	var tables []Table
+
+	// Obtener todas las tablas y vistas. Las vistas materializadas no están
+	// en information_schema, así que se leen de pg_class.
+	query := `
+		SELECT c.relname, obj_description(c.oid, 'pg_class'), c.relkind
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 
+		AND c.relkind IN ('r', 'p', 'v', 'm')
+		ORDER BY c.relname
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %v", err)
+	}
+	defer rows.Close()
+
+	materialized := make(map[string]bool)
+	for rows.Next() {
+		var tableName, relkind string
+		var comment sql.NullString
+		if err := rows.Scan(&tableName, &comment, &relkind); err != nil {
+			return nil, err
+		}
+
+		// Filtrar si es necesario
+		if !tableSelected(tableFilter, tableName) {
+			continue
+		}
+
+		materialized[tableName] = relkind == "m"
+		tables = append(tables, Table{
+			Name:    tableName,
+			Schema:  schema,
+			Comment: comment.String,
+			IsView:  relkind == "v" || relkind == "m",
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	err = scanTables(ctx, s.workers, tables, func(ctx context.Context, table *Table) error {
+		var err error
+
+		// Obtener columnas
+		if table.Columns, err = s.getColumns(ctx, schema, table.Name, materialized[table.Name]); err != nil {
+			return err
+		}
+
+		// Obtener claves primarias
+		if table.PrimaryKeys, err = s.getPrimaryKeys(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Obtener claves foráneas
+		if table.ForeignKeys, err = s.GetForeignKeys(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Obtener índices y restricciones únicas
+		if table.Indexes, err = s.getIndexes(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Restricciones CHECK
+		return s.applyChecks(ctx, table)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// matviewColumnsQuery reads the columns of a materialized view from
+// pg_attribute, in the shape of the information_schema query of getColumns.
+const matviewColumnsQuery = `
+	SELECT
+		a.attname,
+		CASE WHEN t.typelem <> 0 AND t.typlen = -1 THEN 'ARRAY' ELSE format_type(a.atttypid, NULL) END,
+		CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END,
+		NULL::text,
+		CASE WHEN a.atttypid IN ('varchar'::regtype, 'bpchar'::regtype) AND a.atttypmod > 4 THEN a.atttypmod - 4 END,
+		col_description(a.attrelid, a.attnum),
+		a.attnum,
+		false,
+		false,
+		ARRAY(
+			SELECT e.enumlabel
+			FROM pg_catalog.pg_enum e
+			WHERE e.enumtypid = a.atttypid
+			ORDER BY e.enumsortorder
+		),
+		t.typname,
+		CASE WHEN t.typelem <> 0 AND t.typlen = -1 THEN format_type(t.typelem, NULL) END,
+		NULL::text,
+		CASE WHEN a.atttypid = 'numeric'::regtype AND a.atttypmod > 4 THEN ((a.atttypmod - 4) >> 16) & 65535 END,
+		CASE WHEN a.atttypid = 'numeric'::regtype AND a.atttypmod > 4 THEN (a.atttypmod - 4) & 65535 END
+	FROM pg_catalog.pg_attribute a
+	JOIN pg_catalog.pg_type t ON t.oid = a.atttypid
+	JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = $1
+		AND c.relname = $2
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+	ORDER BY a.attnum
+`
+
+func (s *postgresScanner) getColumns(ctx context.Context, schema, tableName string, materialized bool) ([]Column, error) {
+	query := `
+		SELECT 
+			c.column_name,
+			c.data_type,
+			c.is_nullable,
+			c.column_default,
+			c.character_maximum_length,
+			pgd.description as column_comment,
+			c.ordinal_position,
+			c.is_identity = 'YES' AS is_identity,
+			c.is_generated = 'ALWAYS' AS is_generated,
+			ARRAY(
+				SELECT e.enumlabel
+				FROM pg_catalog.pg_enum e
+				JOIN pg_catalog.pg_type t ON t.oid = e.enumtypid
+				JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+				WHERE t.typname = c.udt_name AND n.nspname = c.udt_schema
+				ORDER BY e.enumsortorder
+			) AS enum_labels,
+			c.udt_name,
+			(
+				SELECT format_type(t.typelem, NULL)
+				FROM pg_catalog.pg_type t
+				JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+				WHERE c.data_type = 'ARRAY' AND t.typname = c.udt_name AND n.nspname = c.udt_schema
+			) AS element_type,
+			c.domain_name,
+			CASE WHEN c.data_type = 'numeric' THEN c.numeric_precision END,
+			CASE WHEN c.data_type = 'numeric' THEN c.numeric_scale END
+		FROM information_schema.columns c
+		LEFT JOIN pg_catalog.pg_statio_all_tables st 
+			ON c.table_schema = st.schemaname AND c.table_name = st.relname
+		LEFT JOIN pg_catalog.pg_description pgd 
+			ON pgd.objoid = st.relid AND pgd.objsubid = c.ordinal_position
+		WHERE c.table_schema = $1 
+			AND c.table_name = $2
+		ORDER BY c.ordinal_position
+	`
+	if materialized {
+		query = matviewColumnsQuery
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var col Column
+		var isNullable string
+		var defaultValue, maxLength, comment sql.NullString
+		var udtName string
+		var elementType, domain sql.NullString
+		var precision, scale sql.NullInt64
+
+		if err := rows.Scan(
+			&col.Name,
+			&col.DataType,
+			&isNullable,
+			&defaultValue,
+			&maxLength,
+			&comment,
+			&col.Position,
+			&col.IsIdentity,
+			&col.IsGenerated,
+			pq.Array(&col.Enum),
+			&udtName,
+			&elementType,
+			&domain,
+			&precision,
+			&scale,
+		); err != nil {
+			return nil, err
+		}
+
+		// Los tipos propios que no son enum (citext, hstore...) se conocen
+		// por su nombre
+		if col.DataType == "USER-DEFINED" && len(col.Enum) == 0 {
+			col.DataType = udtName
+		}
+		col.ElementType = elementType.String
+		col.Domain = domain.String
+		if precision.Valid {
+			p := int(precision.Int64)
+			col.Precision = &p
+		}
+		if scale.Valid {
+			sc := int(scale.Int64)
+			col.Scale = &sc
+		}
+
+		col.IsNullable = (isNullable == "YES")
+
+		if defaultValue.Valid {
+			val := defaultValue.String
+			col.DefaultValue = &val
+		}
+
+		if maxLength.Valid && maxLength.String != "" {
+			var length int
+			fmt.Sscanf(maxLength.String, "%d", &length)
+			col.MaxLength = &length
+		}
+
+		if comment.Valid {
+			col.Comment = comment.String
+		}
+
+		columns = append(columns, col)
+	}
+
+	return columns, rows.Err()
+}
+
+// applyChecks reads the CHECK constraints of the domains of the columns and
+// then those of the table, as pg_get_expr prints them, into the rules of its
+// columns.
+func (s *postgresScanner) applyChecks(ctx context.Context, table *Table) error {
+	if err := s.applyDomainChecks(ctx, table); err != nil {
+		return err
+	}
+
+	query := `
+		SELECT pg_get_expr(conbin, conrelid)
+		FROM pg_constraint
+		WHERE conrelid = (quote_ident($1) || '.' || quote_ident($2))::regclass
+			AND contype = 'c'
+		ORDER BY conname
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, table.Schema, table.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var expr string
+		if err := rows.Scan(&expr); err != nil {
+			return err
+		}
+		applyCheck(table, expr, FlavorPostgres)
+	}
+
+	return rows.Err()
+}
+
+func (s *postgresScanner) applyDomainChecks(ctx context.Context, table *Table) error {
+	query := `
+		SELECT a.attname, pg_get_expr(dc.conbin, 0)
+		FROM pg_attribute a
+		JOIN pg_constraint dc ON dc.contypid = a.atttypid AND dc.contype = 'c'
+		WHERE a.attrelid = (quote_ident($1) || '.' || quote_ident($2))::regclass
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+		ORDER BY a.attnum, dc.conname
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, table.Schema, table.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, expr string
+		if err := rows.Scan(&name, &expr); err != nil {
+			return err
+		}
+		if col := findColumn(table, name); col != nil {
+			applyDomainCheck(col, expr, FlavorPostgres)
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *postgresScanner) getPrimaryKeys(ctx context.Context, schema, tableName string) ([]string, error) {
+	query := `
+		SELECT a.attname
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = (quote_ident($1) || '.' || quote_ident($2))::regclass
+			AND i.indisprimary
+		ORDER BY array_position(i.indkey::int2[], a.attnum)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var primaryKeys []string
+	for rows.Next() {
+		var columnName string
+		if err := rows.Scan(&columnName); err != nil {
+			return nil, err
+		}
+		primaryKeys = append(primaryKeys, columnName)
+	}
+
+	return primaryKeys, rows.Err()
+}
+
+// GetForeignKeys reads pg_constraint, which keeps the columns of composite
+// keys paired with the referenced ones (information_schema only relates each
+// column to the whole referenced key).
+func (s *postgresScanner) GetForeignKeys(ctx context.Context, schema, tableName string) ([]ForeignKey, error) {
+	query := `
+		SELECT
+			c.conname,
+			rn.nspname AS referenced_schema,
+			rt.relname AS referenced_table,
+			a.attname AS column_name,
+			ra.attname AS referenced_column
+		FROM pg_constraint c
+		JOIN pg_class rt ON rt.oid = c.confrelid
+		JOIN pg_namespace rn ON rn.oid = rt.relnamespace
+		CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord)
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refattnum
+		WHERE c.contype = 'f'
+			AND c.conrelid = (quote_ident($1) || '.' || quote_ident($2))::regclass
+		ORDER BY c.conname, k.ord
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var name, refSchema, refTable, column, refColumn string
+		if err := rows.Scan(&name, &refSchema, &refTable, &column, &refColumn); err != nil {
+			return nil, err
+		}
+		foreignKeys = appendForeignKeyColumn(foreignKeys, name, refSchema, refTable, column, refColumn)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+// getIndexes reads the plain-column indexes of a table. Expression and partial
+// indexes are left out: they do not make a column unique or sortable as is.
+func (s *postgresScanner) getIndexes(ctx context.Context, schema, tableName string) ([]Index, error) {
+	query := `
+		SELECT i.relname, ix.indisunique, ix.indisprimary, a.attname
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = $1
+			AND t.relname = $2
+			AND ix.indexprs IS NULL
+			AND ix.indpred IS NULL
+		ORDER BY i.relname, k.ord
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var name, column string
+		var unique, primary bool
+		if err := rows.Scan(&name, &unique, &primary, &column); err != nil {
+			return nil, err
+		}
+		indexes = appendIndexColumn(indexes, name, unique, primary, column)
+	}
+
+	return indexes, rows.Err()
+}
@@ -0,0 +1,350 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	Register(Dialect{
+		Name:    "sqlite",
+		Label:   "SQLite",
+		Aliases: []string{"sqlite3"},
+		New:     func() DatabaseScanner { return &sqliteScanner{} },
+	})
+}
+
+type sqliteScanner struct {
+	db *sql.DB
+}
+
+// Connect opens the database file named by config.Database read-only. Host,
+// port and credentials do not apply.
+func (s *sqliteScanner) Connect(ctx context.Context, config *DatabaseConfig) error {
+	if config.Database == "" {
+		return fmt.Errorf("sqlite requires the path of the database file")
+	}
+	if _, err := os.Stat(config.Database); err != nil {
+		return fmt.Errorf("error opening sqlite database: %v", err)
+	}
+
+	db, err := openDB(ctx, "sqlite3", fmt.Sprintf("file:%s?mode=ro", config.Database))
+	if err != nil {
+		return err
+	}
+	// PRAGMA results depend on the connection (attached databases)
+	db.SetMaxOpenConns(1)
+	s.db = db
+	return nil
+}
+
+func (s *sqliteScanner) Disconnect() {
+	closeDB(s.db)
+}
+
+// SQLite has no schemas: every file is the "main" database (plus attached
+// ones). The schema of the connection is kept in the tables so the stored
+// metadata matches, but the catalog is read from main unless the connection
+// names an attached database.
+func sqliteSchema(schema string) string {
+	if schema == "" || strings.EqualFold(schema, "public") {
+		return "main"
+	}
+	return schema
+}
+
+// sqliteIdent quotes an identifier for PRAGMA statements, which do not take
+// bind parameters.
+func sqliteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// VARCHAR(120), NVARCHAR (50), CHARACTER(2)...
+var sqliteLengthRe = regexp.MustCompile(`^\s*[A-Za-z ]+\(\s*(\d+)\s*\)`)
+
+func (s *sqliteScanner) GetTables(ctx context.Context, schema string, tableFilter []string) ([]Table, error) {
+	var tables []Table
+
+	query := fmt.Sprintf(`
+		SELECT name, type
+		FROM %s.sqlite_master
+		WHERE type IN ('table', 'view')
+			AND name NOT LIKE 'sqlite_%%'
+		ORDER BY name
+	`, sqliteIdent(sqliteSchema(schema)))
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %v", err)
+	}
+
+	// The scanner keeps a single SQLite connection, so the names are
+	// collected before querying each table, one after the other.
+	var names []string
+	views := make(map[string]bool)
+	for rows.Next() {
+		var tableName, tableType string
+		if err := rows.Scan(&tableName, &tableType); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, tableName)
+		views[tableName] = tableType == "view"
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tableName := range names {
+		// Filtrar si es necesario
+		if !tableSelected(tableFilter, tableName) {
+			continue
+		}
+
+		// Columnas y claves primarias salen del mismo PRAGMA
+		columns, primaryKeys, err := s.getColumns(ctx, schema, tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		// Obtener claves foráneas
+		foreignKeys, err := s.GetForeignKeys(ctx, schema, tableName)
+		if err != nil {
+			return nil, err
+		}
+
+		// Obtener índices y restricciones únicas
+		indexes, err := s.getIndexes(ctx, schema, tableName, primaryKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		table := Table{
+			Name:        tableName,
+			Schema:      schema,
+			Columns:     columns,
+			PrimaryKeys: primaryKeys,
+			ForeignKeys: foreignKeys,
+			Indexes:     indexes,
+			IsView:      views[tableName],
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// getColumns reads PRAGMA table_xinfo, which also tells the position of
+// every column in the primary key and which columns are generated. A
+// single INTEGER PRIMARY KEY is the rowid, numbered by SQLite.
+func (s *sqliteScanner) getColumns(ctx context.Context, schema, tableName string) ([]Column, []string, error) {
+	query := fmt.Sprintf("PRAGMA %s.table_xinfo(%s)", sqliteIdent(sqliteSchema(schema)), sqliteIdent(tableName))
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	pkPosition := make(map[string]int)
+	for rows.Next() {
+		var col Column
+		var cid, notNull, pk, hidden int
+		var dataType string
+		var defaultValue sql.NullString
+
+		if err := rows.Scan(&cid, &col.Name, &dataType, &notNull, &defaultValue, &pk, &hidden); err != nil {
+			return nil, nil, err
+		}
+		// 1: hidden column of a virtual table; 2, 3: VIRTUAL or STORED generated
+		if hidden == 1 {
+			continue
+		}
+		col.Position = cid + 1
+		col.IsGenerated = hidden == 2 || hidden == 3
+
+		col.DataType = strings.ToLower(dataType)
+		// Las claves primarias admiten NULL en SQLite salvo INTEGER PRIMARY KEY,
+		// pero el API las trata siempre como obligatorias.
+		col.IsNullable = notNull == 0 && pk == 0
+
+		if defaultValue.Valid {
+			val := defaultValue.String
+			col.DefaultValue = &val
+		}
+
+		if m := sqliteLengthRe.FindStringSubmatch(dataType); m != nil {
+			var length int
+			fmt.Sscanf(m[1], "%d", &length)
+			col.MaxLength = &length
+			col.DataType = strings.ToLower(strings.TrimSpace(dataType[:strings.Index(dataType, "(")]))
+		}
+
+		if pk > 0 {
+			pkPosition[col.Name] = pk
+		}
+
+		columns = append(columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var primaryKeys []string
+	for name := range pkPosition {
+		primaryKeys = append(primaryKeys, name)
+	}
+	sort.Slice(primaryKeys, func(i, j int) bool {
+		return pkPosition[primaryKeys[i]] < pkPosition[primaryKeys[j]]
+	})
+
+	if len(primaryKeys) == 1 {
+		for i := range columns {
+			if columns[i].Name == primaryKeys[0] && columns[i].DataType == "integer" {
+				columns[i].IsIdentity = true
+			}
+		}
+	}
+
+	return columns, primaryKeys, nil
+}
+
+func (s *sqliteScanner) getPrimaryKeys(ctx context.Context, schema, tableName string) ([]string, error) {
+	_, primaryKeys, err := s.getColumns(ctx, schema, tableName)
+	return primaryKeys, err
+}
+
+// GetForeignKeys reads PRAGMA foreign_key_list. SQLite does not name
+// the constraints, so the name is built from the table and the constraint id.
+// A reference without columns points to the primary key of the parent table,
+// which is always in the same database file.
+func (s *sqliteScanner) GetForeignKeys(ctx context.Context, schema, tableName string) ([]ForeignKey, error) {
+	query := fmt.Sprintf("PRAGMA %s.foreign_key_list(%s)", sqliteIdent(sqliteSchema(schema)), sqliteIdent(tableName))
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var id, seq int
+		var refTable, from string
+		var to sql.NullString
+		var onUpdate, onDelete, match string
+
+		if err := rows.Scan(&id, &seq, &refTable, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		foreignKeys = appendForeignKeyColumn(foreignKeys, fmt.Sprintf("fk_%s_%d", tableName, id), schema, refTable, from, to.String)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, fk := range foreignKeys {
+		if fk.ReferencedColumns[0] != "" {
+			continue
+		}
+		primaryKeys, err := s.getPrimaryKeys(ctx, schema, fk.ReferencedTable)
+		if err != nil {
+			return nil, err
+		}
+		for k := range fk.ReferencedColumns {
+			if k < len(primaryKeys) {
+				foreignKeys[i].ReferencedColumns[k] = primaryKeys[k]
+			}
+		}
+	}
+
+	return foreignKeys, nil
+}
+
+// getIndexes reads PRAGMA index_list and index_info. Partial and expression
+// indexes are left out. An INTEGER PRIMARY KEY is the rowid and has no index
+// of its own, so it is reported from the primary key columns.
+func (s *sqliteScanner) getIndexes(ctx context.Context, schema, tableName string, primaryKeys []string) ([]Index, error) {
+	db := sqliteIdent(sqliteSchema(schema))
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA %s.index_list(%s)", db, sqliteIdent(tableName)))
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []Index
+	for rows.Next() {
+		var seq, unique, partial int
+		var idx Index
+		var origin string
+		if err := rows.Scan(&seq, &idx.Name, &unique, &origin, &partial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if partial != 0 {
+			continue
+		}
+		idx.Unique = unique != 0
+		idx.Primary = origin == "pk"
+		indexes = append(indexes, idx)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []Index
+	hasPrimary := false
+	for _, idx := range indexes {
+		cols, err := s.indexColumns(ctx, db, idx.Name)
+		if err != nil {
+			return nil, err
+		}
+		if cols == nil {
+			continue
+		}
+		idx.Columns = cols
+		hasPrimary = hasPrimary || idx.Primary
+		out = append(out, idx)
+	}
+	if !hasPrimary && len(primaryKeys) > 0 {
+		out = append([]Index{{Name: tableName + "_pkey", Columns: primaryKeys, Unique: true, Primary: true}}, out...)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// indexColumns returns the columns of an index, or nil when one of its parts
+// is an expression.
+func (s *sqliteScanner) indexColumns(ctx context.Context, db, indexName string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA %s.index_info(%s)", db, sqliteIdent(indexName)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var seqno, cid int
+		var name sql.NullString
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, err
+		}
+		if !name.Valid {
+			return nil, nil
+		}
+		cols = append(cols, name.String)
+	}
+	return cols, rows.Err()
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	_ "github.com/microsoft/go-mssqldb"
+)
+
+func init() {
+	Register(Dialect{
+		Name:    "sqlserver",
+		Label:   "SQL Server",
+		Aliases: []string{"mssql"},
+		New:     func() DatabaseScanner { return &sqlserverScanner{} },
+	})
+}
+
+type sqlserverScanner struct {
+	db      *sql.DB
+	workers int
+}
+
+// The default schema of SQL Server is dbo; connections created with the
+// Postgres default ("public") are read from dbo.
+func sqlServerSchema(schema string) string {
+	if schema == "" || strings.EqualFold(schema, "public") {
+		return "dbo"
+	}
+	return schema
+}
+
+// sqlServerDSN builds a sqlserver:// URL. SSLMode "disable" turns encryption
+// off, any other value leaves the driver default.
+func sqlServerDSN(config *DatabaseConfig) string {
+	port := config.Port
+	if port == "" {
+		port = "1433"
+	}
+
+	query := url.Values{}
+	query.Set("database", config.Database)
+	if strings.EqualFold(config.SSLMode, "disable") {
+		query.Set("encrypt", "disable")
+	}
+
+	dsn := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(config.Username, config.Password),
+		Host:     fmt.Sprintf("%s:%s", config.Host, port),
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+func (s *sqlserverScanner) Connect(ctx context.Context, config *DatabaseConfig) error {
+	db, err := openDB(ctx, "sqlserver", sqlServerDSN(config))
+	if err != nil {
+		return err
+	}
+	s.db = db
+	s.workers = config.workers()
+	return nil
+}
+
+func (s *sqlserverScanner) Disconnect() {
+	closeDB(s.db)
+}
+
+// GetTables lists the tables and views of the schema and introspects them
+// with a pool of workers, one table per worker at a time.
+func (s *sqlserverScanner) GetTables(ctx context.Context, schema string, tableFilter []string) ([]Table, error) {
+	var tables []Table
+
+	query := `
+		SELECT t.TABLE_NAME, CAST(ep.value AS nvarchar(4000)), t.TABLE_TYPE
+		FROM INFORMATION_SCHEMA.TABLES t
+		LEFT JOIN sys.extended_properties ep
+			ON ep.class = 1
+			AND ep.major_id = OBJECT_ID(QUOTENAME(t.TABLE_SCHEMA) + '.' + QUOTENAME(t.TABLE_NAME))
+			AND ep.minor_id = 0
+			AND ep.name = 'MS_Description'
+		WHERE t.TABLE_SCHEMA = @p1
+			AND t.TABLE_TYPE IN ('BASE TABLE', 'VIEW')
+		ORDER BY t.TABLE_NAME
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, sqlServerSchema(schema))
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, tableType string
+		var comment sql.NullString
+		if err := rows.Scan(&tableName, &comment, &tableType); err != nil {
+			return nil, err
+		}
+
+		// Filtrar si es necesario
+		if !tableSelected(tableFilter, tableName) {
+			continue
+		}
+
+		tables = append(tables, Table{
+			Name:    tableName,
+			Schema:  schema,
+			Comment: comment.String,
+			IsView:  tableType == "VIEW",
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	err = scanTables(ctx, s.workers, tables, func(ctx context.Context, table *Table) error {
+		var err error
+
+		// Obtener columnas
+		if table.Columns, err = s.getColumns(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Obtener claves primarias
+		if table.PrimaryKeys, err = s.getPrimaryKeys(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Obtener claves foráneas
+		if table.ForeignKeys, err = s.GetForeignKeys(ctx, schema, table.Name); err != nil {
+			return err
+		}
+
+		// Obtener índices y restricciones únicas
+		table.Indexes, err = s.getIndexes(ctx, schema, table.Name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// getColumns reads the columns with their MS_Description extended
+// property as comment. CHARACTER_MAXIMUM_LENGTH is -1 for (max) types, which
+// have no length limit. COLUMNPROPERTY tells identity and computed columns.
+func (s *sqlserverScanner) getColumns(ctx context.Context, schema, tableName string) ([]Column, error) {
+	query := `
+		SELECT
+			c.COLUMN_NAME,
+			c.DATA_TYPE,
+			c.IS_NULLABLE,
+			c.COLUMN_DEFAULT,
+			c.CHARACTER_MAXIMUM_LENGTH,
+			CAST(ep.value AS nvarchar(4000)) AS column_comment,
+			c.ORDINAL_POSITION,
+			COLUMNPROPERTY(OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME)), c.COLUMN_NAME, 'IsIdentity') AS is_identity,
+			COLUMNPROPERTY(OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME)), c.COLUMN_NAME, 'IsComputed') AS is_computed,
+			CASE WHEN c.DATA_TYPE IN ('decimal', 'numeric') THEN c.NUMERIC_PRECISION END,
+			CASE WHEN c.DATA_TYPE IN ('decimal', 'numeric') THEN c.NUMERIC_SCALE END
+		FROM INFORMATION_SCHEMA.COLUMNS c
+		LEFT JOIN sys.extended_properties ep
+			ON ep.class = 1
+			AND ep.major_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME))
+			AND ep.minor_id = COLUMNPROPERTY(ep.major_id, c.COLUMN_NAME, 'ColumnId')
+			AND ep.name = 'MS_Description'
+		WHERE c.TABLE_SCHEMA = @p1
+			AND c.TABLE_NAME = @p2
+		ORDER BY c.ORDINAL_POSITION
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, sqlServerSchema(schema), tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var col Column
+		var isNullable string
+		var defaultValue, comment sql.NullString
+		var maxLength, isIdentity, isComputed, precision, scale sql.NullInt64
+
+		if err := rows.Scan(
+			&col.Name,
+			&col.DataType,
+			&isNullable,
+			&defaultValue,
+			&maxLength,
+			&comment,
+			&col.Position,
+			&isIdentity,
+			&isComputed,
+			&precision,
+			&scale,
+		); err != nil {
+			return nil, err
+		}
+
+		col.IsNullable = (isNullable == "YES")
+
+		if defaultValue.Valid {
+			val := defaultValue.String
+			col.DefaultValue = &val
+		}
+
+		if maxLength.Valid && maxLength.Int64 > 0 {
+			length := int(maxLength.Int64)
+			col.MaxLength = &length
+		}
+
+		if comment.Valid {
+			col.Comment = comment.String
+		}
+
+		if precision.Valid && scale.Valid {
+			p, sc := int(precision.Int64), int(scale.Int64)
+			col.Precision, col.Scale = &p, &sc
+		}
+
+		col.IsIdentity = isIdentity.Int64 == 1
+		col.IsGenerated = isComputed.Int64 == 1
+
+		columns = append(columns, col)
+	}
+
+	return columns, rows.Err()
+}
+
+func (s *sqlserverScanner) getPrimaryKeys(ctx context.Context, schema, tableName string) ([]string, error) {
+	query := `
+		SELECT kcu.COLUMN_NAME
+		FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+			ON kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+			AND kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+			AND kcu.TABLE_NAME = tc.TABLE_NAME
+		WHERE tc.CONSTRAINT_TYPE = 'PRIMARY KEY'
+			AND tc.TABLE_SCHEMA = @p1
+			AND tc.TABLE_NAME = @p2
+		ORDER BY kcu.ORDINAL_POSITION
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, sqlServerSchema(schema), tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var primaryKeys []string
+	for rows.Next() {
+		var columnName string
+		if err := rows.Scan(&columnName); err != nil {
+			return nil, err
+		}
+		primaryKeys = append(primaryKeys, columnName)
+	}
+
+	return primaryKeys, rows.Err()
+}
+
+// GetForeignKeys reads sys.foreign_key_columns, which pairs every
+// column with the referenced one (INFORMATION_SCHEMA only has the referenced
+// unique constraint).
+func (s *sqlserverScanner) GetForeignKeys(ctx context.Context, schema, tableName string) ([]ForeignKey, error) {
+	query := `
+		SELECT
+			fk.name AS constraint_name,
+			SCHEMA_NAME(rt.schema_id) AS referenced_schema,
+			rt.name AS referenced_table,
+			pc.name AS column_name,
+			rc.name AS referenced_column
+		FROM sys.foreign_keys fk
+		JOIN sys.foreign_key_columns fkc
+			ON fkc.constraint_object_id = fk.object_id
+		JOIN sys.columns pc
+			ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+		JOIN sys.tables rt
+			ON rt.object_id = fkc.referenced_object_id
+		JOIN sys.columns rc
+			ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+		WHERE fk.parent_object_id = OBJECT_ID(QUOTENAME(@p1) + '.' + QUOTENAME(@p2))
+		ORDER BY fk.name, fkc.constraint_column_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, sqlServerSchema(schema), tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var name, refSchema, refTable, column, refColumn string
+		if err := rows.Scan(&name, &refSchema, &refTable, &column, &refColumn); err != nil {
+			return nil, err
+		}
+		// Same schema as the table, kept under the name of the connection
+		if strings.EqualFold(refSchema, sqlServerSchema(schema)) {
+			refSchema = schema
+		}
+		foreignKeys = appendForeignKeyColumn(foreignKeys, name, refSchema, refTable, column, refColumn)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+// getIndexes reads sys.indexes without filtered indexes and included
+// (non-key) columns.
+func (s *sqlserverScanner) getIndexes(ctx context.Context, schema, tableName string) ([]Index, error) {
+	query := `
+		SELECT i.name, i.is_unique, i.is_primary_key, c.name
+		FROM sys.indexes i
+		JOIN sys.index_columns ic
+			ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN sys.columns c
+			ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(QUOTENAME(@p1) + '.' + QUOTENAME(@p2))
+			AND i.name IS NOT NULL
+			AND i.has_filter = 0
+			AND ic.is_included_column = 0
+		ORDER BY i.name, ic.key_ordinal
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, sqlServerSchema(schema), tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var name, column string
+		var unique, primary bool
+		if err := rows.Scan(&name, &unique, &primary, &column); err != nil {
+			return nil, err
+		}
+		indexes = appendIndexColumn(indexes, name, unique, primary, column)
+	}
+
+	return indexes, rows.Err()
+}
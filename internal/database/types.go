@@ -1,5 +1,11 @@
 package database
 
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
 type Column struct {
 	Name         string
 	DataType     string
@@ -9,6 +15,39 @@ type Column struct {
 	DefaultValue *string
 	MaxLength    *int
 	Comment      string
+	Position     int  // Ordinal position in the table, from 1
+	IsIdentity   bool // Numbered by the database (identity, auto_increment)
+	IsGenerated  bool // Computed from other columns; cannot be written
+
+	// Declared type details: DataType keeps the information_schema name
+	// ("ARRAY", "numeric") and these tell the rest
+	ElementType string // Type of the elements of an ARRAY column
+	Domain      string // Domain the column is declared with; DataType is its base type
+	Precision   *int   // Precision and scale of numeric/decimal columns
+	Scale       *int
+
+	// Rules read from CHECK constraints and enumerated types
+	Enum    []string
+	Min     *float64
+	Max     *float64
+	Pattern string
+}
+
+// FullType is the declared type of the column: "integer[]" for arrays,
+// "numeric(12,2)" for numerics with precision and "email (citext)" for a
+// domain.
+func (c Column) FullType() string {
+	base := c.DataType
+	switch {
+	case c.ElementType != "":
+		base = c.ElementType + "[]"
+	case c.Precision != nil && c.Scale != nil:
+		base = fmt.Sprintf("%s(%d,%d)", c.DataType, *c.Precision, *c.Scale)
+	}
+	if c.Domain != "" {
+		return c.Domain + " (" + base + ")"
+	}
+	return base
 }
 
 type Table struct {
@@ -17,21 +56,139 @@ type Table struct {
 	Columns     []Column
 	PrimaryKeys []string
 	ForeignKeys []ForeignKey
+	Indexes     []Index
 	Comment     string
+	IsView      bool // Views and materialized views: read-only
+}
+
+// Index is an index or a unique constraint (which databases back with an
+// index), in column order. The primary key is reported too, flagged Primary.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
 }
 
+// IsUniqueColumn reports whether a column alone is unique, besides the
+// primary key.
+func (t Table) IsUniqueColumn(name string) bool {
+	for _, idx := range t.Indexes {
+		if idx.Unique && !idx.Primary && len(idx.Columns) == 1 && strings.EqualFold(idx.Columns[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIndexedColumn reports whether a column leads an index, so filtering or
+// sorting on it can use the index.
+func (t Table) IsIndexedColumn(name string) bool {
+	for _, idx := range t.Indexes {
+		if len(idx.Columns) > 0 && strings.EqualFold(idx.Columns[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendIndexColumn adds a column to the index of that name, creating the
+// index when the rows of a new one start. Scanners read one row per column,
+// ordered by index and position.
+func appendIndexColumn(indexes []Index, name string, unique, primary bool, column string) []Index {
+	if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+		indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+		return indexes
+	}
+	return append(indexes, Index{Name: name, Columns: []string{column}, Unique: unique, Primary: primary})
+}
+
+// ForeignKey references the primary (or a unique) key of another table.
+// Columns and ReferencedColumns are paired by position; composite keys have
+// more than one. ReferencedSchema is the schema of the referenced table, which
+// can differ from the one of the key when a connection scans several schemas.
 type ForeignKey struct {
-	ColumnName       string
-	ReferencedTable  string
-	ReferencedColumn string
-	ConstraintName   string
+	Columns           []string
+	ReferencedSchema  string
+	ReferencedTable   string
+	ReferencedColumns []string
+	ConstraintName    string
+}
+
+// References reports whether the key points to a table. A key without
+// ReferencedSchema (stored before schemas were recorded) matches by name.
+func (fk ForeignKey) References(schema, name string) bool {
+	return strings.EqualFold(fk.ReferencedTable, name) &&
+		(fk.ReferencedSchema == "" || strings.EqualFold(fk.ReferencedSchema, schema))
+}
+
+// QualifiedReferencedTable returns the referenced table, prefixed with its
+// schema when it lives outside the given one: "roles" from public,
+// "security.users" from sales.
+func (fk ForeignKey) QualifiedReferencedTable(schema string) string {
+	if fk.ReferencedSchema == "" || strings.EqualFold(fk.ReferencedSchema, schema) {
+		return fk.ReferencedTable
+	}
+	return fk.ReferencedSchema + "." + fk.ReferencedTable
+}
+
+// HasColumn reports whether a column is part of the key.
+func (fk ForeignKey) HasColumn(name string) bool {
+	return fk.position(name) >= 0
+}
+
+// ReferencedColumnOf returns the column referenced by a column of the key.
+func (fk ForeignKey) ReferencedColumnOf(name string) string {
+	if i := fk.position(name); i >= 0 && i < len(fk.ReferencedColumns) {
+		return fk.ReferencedColumns[i]
+	}
+	return ""
+}
+
+func (fk ForeignKey) position(name string) int {
+	for i, c := range fk.Columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ForeignKeyOf returns the foreign key a column belongs to.
+func (t Table) ForeignKeyOf(name string) (ForeignKey, bool) {
+	for _, fk := range t.ForeignKeys {
+		if fk.HasColumn(name) {
+			return fk, true
+		}
+	}
+	return ForeignKey{}, false
+}
+
+// appendForeignKeyColumn adds a column pair to the foreign key of that name,
+// like appendIndexColumn. Rows must be ordered by constraint and position.
+func appendForeignKeyColumn(foreignKeys []ForeignKey, name, referencedSchema, referencedTable, column, referencedColumn string) []ForeignKey {
+	if n := len(foreignKeys); n > 0 && foreignKeys[n-1].ConstraintName == name {
+		foreignKeys[n-1].Columns = append(foreignKeys[n-1].Columns, column)
+		foreignKeys[n-1].ReferencedColumns = append(foreignKeys[n-1].ReferencedColumns, referencedColumn)
+		return foreignKeys
+	}
+	return append(foreignKeys, ForeignKey{
+		Columns:           []string{column},
+		ReferencedSchema:  referencedSchema,
+		ReferencedTable:   referencedTable,
+		ReferencedColumns: []string{referencedColumn},
+		ConstraintName:    name,
+	})
 }
 
+// DatabaseScanner introspects one database dialect. Implementations register
+// themselves with Register and are obtained with NewScanner. The context
+// bounds the whole call: cancelling it aborts the queries in flight.
 type DatabaseScanner interface {
-	Connect(config *DatabaseConfig) error
+	Connect(ctx context.Context, config *DatabaseConfig) error
 	Disconnect()
-	GetTables(schema string, tableFilter []string) ([]Table, error)
-	GetForeignKeys(schema, tableName string) ([]ForeignKey, error)
+	GetTables(ctx context.Context, schema string, tableFilter []string) ([]Table, error)
+	GetForeignKeys(ctx context.Context, schema, tableName string) ([]ForeignKey, error)
 }
 
 type DatabaseConfig struct {
@@ -43,4 +200,6 @@ type DatabaseConfig struct {
 	Database string
 	SSLMode  string
 	Timezone string
+	Schemas  []string // schemas scanned; the first one takes unqualified names
+	Workers  int      // tables introspected at the same time, DefaultScanWorkers when 0
 }
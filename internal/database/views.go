@@ -0,0 +1,228 @@
+package database
+
+import "strings"
+
+// viewKeyword returns the position of VIEW in CREATE [OR REPLACE]
+// [MATERIALIZED] VIEW, after MySQL's ALGORITHM=, DEFINER= and SQL SECURITY
+// options, or -1 when the statement creates something else.
+func viewKeyword(tokens []ddlToken, i int) int {
+	for j := i; j < len(tokens); j++ {
+		t := tokens[j]
+		if t.is("VIEW") {
+			return j
+		}
+		if t.is("(") || isAnyWord(t, "AS", "TABLE", "INDEX", "TYPE", "FUNCTION", "PROCEDURE", "TRIGGER", "SEQUENCE", "SCHEMA", "EXTENSION") {
+			return -1
+		}
+	}
+	return -1
+}
+
+// createView reads CREATE VIEW name [(columns)] AS SELECT ... into a read-only
+// table. The columns come from the select list: columns of the tables in FROM
+// (t.*, t.c, c) keep their type; other expressions need an alias and are read
+// as text. An explicit column list renames them.
+func (p *ddlParser) createView(tokens []ddlToken, i int) error {
+	if i+2 < len(tokens) && tokens[i].is("IF") && tokens[i+1].is("NOT") && tokens[i+2].is("EXISTS") {
+		i += 3
+	}
+	schema, name, i, err := p.qualifiedName(tokens, i)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if i < len(tokens) && tokens[i].is("(") {
+		if names, i, err = p.columnList(tokens, i); err != nil {
+			return err
+		}
+	}
+	if i >= len(tokens) || !tokens[i].is("AS") {
+		return nil
+	}
+
+	columns := p.viewColumns(unwrapParens(tokens[i+1:]))
+	for k := range columns {
+		if k < len(names) {
+			columns[k].Name = names[k]
+		}
+		columns[k].Position = k + 1
+	}
+
+	table := &Table{Name: name, Schema: schema, Columns: columns, IsView: true}
+	if existing := p.findTable(schema, name); existing != nil {
+		*existing = *table
+	} else {
+		p.tables = append(p.tables, table)
+	}
+	return nil
+}
+
+// viewSource is a table of the FROM clause of a view.
+type viewSource struct {
+	alias string
+	table *Table
+}
+
+// Words of a FROM clause: viewStop ones end it, viewJoin ones are part of
+// joins and never an alias.
+var (
+	viewStop = map[string]bool{"WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true, "LIMIT": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "WINDOW": true, "WITH": true}
+	viewJoin = map[string]bool{"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true, "OUTER": true, "NATURAL": true, "ON": true, "USING": true, "STRAIGHT_JOIN": true}
+)
+
+func (p *ddlParser) viewColumns(sel []ddlToken) []Column {
+	if len(sel) == 0 || !sel[0].is("SELECT") {
+		return nil
+	}
+	k := 1
+	if k < len(sel) && sel[k].is("ALL") {
+		k++
+	}
+	if k < len(sel) && sel[k].is("DISTINCT") {
+		k++
+		if k < len(sel) && sel[k].is("ON") && k+1 < len(sel) {
+			if end := matchParen(sel, k+1); end > 0 {
+				k = end + 1
+			}
+		}
+	}
+	from := len(sel)
+	for j, depth := k, 0; j < len(sel); j++ {
+		switch {
+		case sel[j].is("("):
+			depth++
+		case sel[j].is(")"):
+			depth--
+		case depth == 0 && sel[j].is("FROM"):
+			from = j
+		}
+		if from < len(sel) {
+			break
+		}
+	}
+	sources := p.viewSources(sel[from:])
+
+	var columns []Column
+	for _, item := range splitTopLevel(sel[k:from]) {
+		if len(item) == 0 {
+			continue
+		}
+		alias := ""
+		n := len(item)
+		if n > 2 && item[n-2].is("AS") {
+			alias, item = p.ident(item[n-1]), item[:n-2]
+		} else if n > 1 && (item[n-1].kind == tokIdent || (item[n-1].kind == tokWord && !item[n-1].is("END"))) &&
+			(item[n-2].kind == tokWord || item[n-2].kind == tokIdent || item[n-2].is(")")) {
+			// Alias without AS: "u.name username", "count(*) total"
+			alias, item = p.ident(item[n-1]), item[:n-1]
+		}
+
+		switch {
+		case len(item) == 1 && item[0].is("*"):
+			for _, src := range sources {
+				columns = append(columns, viewCopy(src.table.Columns)...)
+			}
+			continue
+		case len(item) == 3 && item[1].is(".") && item[2].is("*"):
+			for _, src := range sources {
+				if strings.EqualFold(src.alias, p.ident(item[0])) {
+					columns = append(columns, viewCopy(src.table.Columns)...)
+				}
+			}
+			continue
+		}
+
+		var col *Column
+		switch {
+		case len(item) == 1 && (item[0].kind == tokWord || item[0].kind == tokIdent):
+			col = viewColumn(sources, "", p.ident(item[0]))
+		case len(item) == 3 && item[1].is(".") && (item[2].kind == tokWord || item[2].kind == tokIdent):
+			col = viewColumn(sources, p.ident(item[0]), p.ident(item[2]))
+		}
+		if col == nil {
+			if alias == "" {
+				// Unnamed expression ("?column?"): nothing to expose
+				continue
+			}
+			columns = append(columns, Column{Name: alias, DataType: "text", IsNullable: true})
+			continue
+		}
+		c := viewCopy([]Column{*col})[0]
+		if alias != "" {
+			c.Name = alias
+		}
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+// viewSources reads the tables of a FROM clause with their aliases. Tables
+// not defined in the script and subqueries are left out.
+func (p *ddlParser) viewSources(tokens []ddlToken) []viewSource {
+	var sources []viewSource
+	expectTable := true
+	for j := 1; j < len(tokens); j++ {
+		t := tokens[j]
+		switch {
+		case t.is("("):
+			end := matchParen(tokens, j)
+			if end < 0 {
+				return sources
+			}
+			j = end
+			expectTable = false
+		case t.is(","), t.is("JOIN"):
+			expectTable = true
+		case t.kind == tokWord && viewStop[strings.ToUpper(t.text)]:
+			return sources
+		case expectTable && (t.kind == tokWord || t.kind == tokIdent) && !viewJoin[strings.ToUpper(t.text)]:
+			schema, name, next, err := p.qualifiedName(tokens, j)
+			if err != nil {
+				return sources
+			}
+			alias := name
+			if next < len(tokens) && tokens[next].is("AS") {
+				next++
+			}
+			if next < len(tokens) && (tokens[next].kind == tokIdent || (tokens[next].kind == tokWord && !viewJoin[strings.ToUpper(tokens[next].text)] && !viewStop[strings.ToUpper(tokens[next].text)])) {
+				alias = p.ident(tokens[next])
+				next++
+			}
+			if table := p.findTable(schema, name); table != nil {
+				sources = append(sources, viewSource{alias: alias, table: table})
+			}
+			j = next - 1
+			expectTable = false
+		}
+	}
+	return sources
+}
+
+// viewColumn finds a column of the FROM tables, in the table of that alias
+// when given.
+func viewColumn(sources []viewSource, alias, name string) *Column {
+	for _, src := range sources {
+		if alias != "" && !strings.EqualFold(src.alias, alias) {
+			continue
+		}
+		if col := findColumn(src.table, name); col != nil {
+			return col
+		}
+	}
+	return nil
+}
+
+// viewCopy returns the columns as a view exposes them: same type and rules,
+// but no keys nor database-filled values.
+func viewCopy(columns []Column) []Column {
+	out := make([]Column, len(columns))
+	for i, c := range columns {
+		c.IsPrimaryKey = false
+		c.IsForeignKey = false
+		c.IsIdentity = false
+		c.IsGenerated = false
+		c.DefaultValue = nil
+		out[i] = c
+	}
+	return out
+}
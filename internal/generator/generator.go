@@ -1,20 +1,26 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"api-scaffolding/internal/backup"
 	"api-scaffolding/internal/database"
 	"api-scaffolding/internal/utils"
 )
 
 type Generator struct {
 	config            *Config
-	dbScanner         *database.Scanner
+	dbScanner         database.DatabaseScanner
 	templateProcessor *TemplateProcessor
 	allTables         []database.Table
+	metadata          map[string]TableMeta
+	typeMaps          typeMapper
+	validationRules   []ValidationRule
 }
 
 type Config struct {
@@ -33,7 +39,32 @@ type Config struct {
 	ProjectRelations []string
 }
 
-func NewGenerator(config *Config, dbScanner *database.Scanner, templateProcessor *TemplateProcessor) *Generator {
+// FieldMeta holds the curated settings of a field as maintained in tablesfields.
+// The Val* settings override the length and validations of the field; zero
+// values leave them as deduced.
+type FieldMeta struct {
+	Label     string
+	LabelHelp string
+	OrderList int
+	InList    bool
+	InCrud    bool
+
+	ValLength  int
+	ValMin     *float64
+	ValMax     *float64
+	ValPattern string
+	ValEnum    []string
+	ValMessage string
+}
+
+// TableMeta holds the curated settings of a table and its fields, keyed by
+// lower-case field name.
+type TableMeta struct {
+	EntityName string
+	Fields     map[string]FieldMeta
+}
+
+func NewGenerator(config *Config, dbScanner database.DatabaseScanner, templateProcessor *TemplateProcessor) *Generator {
 	return &Generator{
 		config:            config,
 		dbScanner:         dbScanner,
@@ -41,11 +72,11 @@ func NewGenerator(config *Config, dbScanner *database.Scanner, templateProcessor
 	}
 }
 
-func (g *Generator) Generate() error {
+func (g *Generator) Generate(ctx context.Context) error {
 	fmt.Println("Starting scaffolding generation...")
 
-	// Obtener tablas de la base de datos
-	tables, err := g.dbScanner.GetTables(g.config.ProjectSchema, g.config.ProjectTables)
+	// Obtener tablas de la base de datos (uno o varios esquemas)
+	tables, err := database.GetTablesIn(ctx, g.dbScanner, database.SplitSchemas(g.config.ProjectSchema), g.config.ProjectTables)
 	if err != nil {
 		return fmt.Errorf("error getting tables: %v", err)
 	}
@@ -78,6 +109,7 @@ func (g *Generator) generateTableFiles(table database.Table) error {
 
 	// Preparar datos para templates
 	templateData := g.prepareTemplateData(table)
+	backups := backup.NewStore(g.config.ProjectDir, backup.DefaultPolicy)
 
 	// Generar archivos basados en templates disponibles
 	templates := []string{
@@ -89,6 +121,11 @@ func (g *Generator) generateTableFiles(table database.Table) error {
 	}
 
 	for _, templateName := range templates {
+		// Las vistas son de solo lectura
+		if table.IsView && !IsReadOnlyTemplate(templateName) {
+			continue
+		}
+
 		// Verificar si el template existe
 		if _, err := os.Stat(filepath.Join("templates", templateName)); os.IsNotExist(err) {
 			// Crear template por defecto si no existe
@@ -103,7 +140,7 @@ func (g *Generator) generateTableFiles(table database.Table) error {
 		outputPath := filepath.Join(entityDir, outputFile)
 
 		// Procesar template
-		if err := g.templateProcessor.ProcessToFile(templateName, templateData, outputPath); err != nil {
+		if err := g.templateProcessor.ProcessToFile(templateName, templateData, outputPath, backups); err != nil {
 			return fmt.Errorf("error processing template %s: %v", templateName, err)
 		}
 
@@ -113,6 +150,38 @@ func (g *Generator) generateTableFiles(table database.Table) error {
 	return nil
 }
 
+// readOnlyTemplates are the templates that never write to the table: the
+// only ones generated for views.
+var readOnlyTemplates = map[string]bool{
+	"entidad_list.tpl":   true,
+	"entidad_get.tpl":    true,
+	"entidad_report.tpl": true,
+}
+
+// IsReadOnlyTemplate reports whether a template, by file name or path, only
+// reads from the table.
+func IsReadOnlyTemplate(templateName string) bool {
+	return readOnlyTemplates[filepath.Base(templateName)]
+}
+
+// SetMetadata registers the curated metadata, keyed by database.TableKey,
+// applied on top of the database facts when preparing template data.
+func (g *Generator) SetMetadata(metadata map[string]TableMeta) {
+	g.metadata = metadata
+}
+
+// SetTypeMaps registers the type mapping rules of the project, in the order
+// they are tried.
+func (g *Generator) SetTypeMaps(typeMaps []TypeMap) {
+	g.typeMaps = typeMaps
+}
+
+// SetValidationRules registers the rules of the validation rule packs of the
+// project, as LoadValidationRules returns them.
+func (g *Generator) SetValidationRules(rules []ValidationRule) {
+	g.validationRules = rules
+}
+
 // PrepareTemplateDataPublic is the exported version of prepareTemplateData.
 // allTables is needed to resolve N:M relations; pass the full list from the scanner.
 func (g *Generator) PrepareTemplateDataPublic(table database.Table, allTables []database.Table) map[string]interface{} {
@@ -120,21 +189,51 @@ func (g *Generator) PrepareTemplateDataPublic(table database.Table, allTables []
 	return g.prepareTemplateData(table)
 }
 
+// EntityName is the entity a table generates: the curated name, unless it is
+// empty or just the table name (as scans store it), else the singular of the
+// table name.
+func EntityName(tableName, curated string) string {
+	if curated != "" && !strings.EqualFold(curated, tableName) {
+		return curated
+	}
+	return singularize(tableName)
+}
+
 func (g *Generator) prepareTemplateData(table database.Table) map[string]interface{} {
 	data := make(map[string]interface{})
 
+	meta, hasMeta := g.metadata[database.TableKey(table.Schema, table.Name)]
+
+	entityName := EntityName(table.Name, meta.EntityName)
+
 	// Información básica de la tabla
 	data["TableName"] = table.Name
 	data["TableNameLower"] = strings.ToLower(table.Name)
-	data["EntityName"] = singularize(table.Name)
+	data["EntityName"] = entityName
 	data["EntityNameTitle"] = utils.TitleFirst(table.Name)
-	data["EntityNameLower"] = strings.ToLower(singularize(table.Name))
+	data["EntityNameLower"] = strings.ToLower(entityName)
 	data["EntityNamePlural"] = pluralize(strings.ToLower(table.Name))
 	data["Schema"] = table.Schema
+	// Nombre para el SQL generado, con esquema si hace falta
+	data["QualifiedName"] = g.qualifiedName(table.Schema, table.Name)
+	data["IsView"] = table.IsView
+	// Comentario de la tabla, en una línea y escapado para las descripciones
+	data["TableComment"] = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(strings.Join(strings.Fields(table.Comment), " "))
+
+	columns := table.Columns
+	if hasMeta {
+		// Respetar el orden definido en tablesfields.orderlist
+		columns = make([]database.Column, len(table.Columns))
+		copy(columns, table.Columns)
+		sort.SliceStable(columns, func(i, j int) bool {
+			return meta.Fields[strings.ToLower(columns[i].Name)].OrderList < meta.Fields[strings.ToLower(columns[j].Name)].OrderList
+		})
+	}
 
 	// Convertir columnas de database a generator
-	fields := make([]map[string]interface{}, len(table.Columns))
-	for i, dbCol := range table.Columns {
+	fields := make([]map[string]interface{}, len(columns))
+	var listFields, crudFields []map[string]interface{}
+	for i, dbCol := range columns {
 		// Convertir database.Column a generator.Column
 		col := Column{
 			Name:         dbCol.Name,
@@ -145,10 +244,43 @@ func (g *Generator) prepareTemplateData(table database.Table) map[string]interfa
 			DefaultValue: dbCol.DefaultValue,
 			MaxLength:    dbCol.MaxLength,
 			Comment:      dbCol.Comment,
+			Position:     dbCol.Position,
+			IsIdentity:   dbCol.IsIdentity,
+			IsGenerated:  dbCol.IsGenerated,
+			Enum:         dbCol.Enum,
+			Min:          dbCol.Min,
+			Max:          dbCol.Max,
+			Pattern:      dbCol.Pattern,
+			ElementType:  dbCol.ElementType,
+			Domain:       dbCol.Domain,
+			Precision:    dbCol.Precision,
+			Scale:        dbCol.Scale,
 		}
 
-		fieldType := formatType(col.DataType)
-		isRequired := !col.IsNullable && col.DefaultValue == nil
+		mapping := g.typeMaps.resolve(col)
+		fieldType := mapping.Type
+		// Identity and generated columns are filled by the database
+		isRequired := !col.IsNullable && col.DefaultValue == nil && !col.IsIdentity && !col.IsGenerated
+
+		// Label and help default to the column name unless curated otherwise.
+		fieldMeta := FieldMeta{OrderList: i + 1, InList: true, InCrud: true}
+		if hasMeta {
+			if fm, ok := meta.Fields[strings.ToLower(col.Name)]; ok {
+				fieldMeta = fm
+			}
+		}
+		if fieldMeta.ValLength > 0 {
+			length := fieldMeta.ValLength
+			col.MaxLength = &length
+		}
+		label := toPascalCase(col.Name)
+		if fieldMeta.Label != "" && fieldMeta.Label != col.Name {
+			label = fieldMeta.Label
+		}
+		labelHelp := ""
+		if fieldMeta.LabelHelp != col.Name {
+			labelHelp = fieldMeta.LabelHelp
+		}
 
 		field := map[string]interface{}{
 			"Name":         col.Name,
@@ -156,35 +288,100 @@ func (g *Generator) prepareTemplateData(table database.Table) map[string]interfa
 			"NameCamel":    toCamelCase(col.Name),
 			"NamePascal":   toPascalCase(col.Name),
 			"Type":         fieldType,
+			"Format":       mapping.Format,
 			"DBType":       col.DataType,
+			"Domain":       col.Domain,
 			"IsRequired":   isRequired,
 			"IsPrimaryKey": g.isPrimaryKey(col.Name, table.PrimaryKeys),
 			"IsForeignKey": col.IsForeignKey,
-			"Validation":   getValidation(col, isRequired),
+			"IsUnique":     table.IsUniqueColumn(col.Name),
+			"IsIndexed":    table.IsIndexedColumn(col.Name) || g.isPrimaryKey(col.Name, table.PrimaryKeys),
+			"Validation":   overrideValidation(validationFor(col, isRequired, mapping, g.validationRules), fieldMeta, fieldType),
 			"Default":      getDefault(col, fieldType),
 			"MaxLength":    col.MaxLength,
 			"Comment":      col.Comment,
+			"Position":     col.Position,
+			"IsIdentity":   col.IsIdentity,
+			"IsGenerated":  col.IsGenerated,
+			"Label":        label,
+			"LabelHelp":    labelHelp,
+			"OrderList":    fieldMeta.OrderList,
+			"InList":       fieldMeta.InList,
+			"InCrud":       fieldMeta.InCrud,
 		}
 
 		fields[i] = field
+		if fieldMeta.InList {
+			listFields = append(listFields, field)
+		}
+		if fieldMeta.InCrud {
+			crudFields = append(crudFields, field)
+		}
 	}
 
+	data["ListFields"] = listFields
+	data["CrudFields"] = crudFields
+
+	// Columnas del SELECT de listados: "*" salvo que se hayan excluido campos
+	listColumns := "*"
+	if len(listFields) < len(fields) {
+		names := make([]string, len(listFields))
+		for i, f := range listFields {
+			names[i] = f["Name"].(string)
+		}
+		listColumns = strings.Join(names, ", ")
+	}
+	data["ListColumns"] = listColumns
 	data["Fields"] = fields
 	data["PrimaryKeys"] = table.PrimaryKeys
 
+	// Parámetros de ruta y condiciones que identifican un registro
+	keyParams := g.keyParams(table, fields)
+	var paths, conditions, keyColumns, cacheParts []string
+	for _, k := range keyParams {
+		paths = append(paths, ":"+k["Name"].(string))
+		conditions = append(conditions, fmt.Sprintf("%s = :%s", k["Column"], k["Name"]))
+		keyColumns = append(keyColumns, k["Column"].(string))
+		cacheParts = append(cacheParts, "{{."+k["Name"].(string)+"}}")
+	}
+	data["KeyParams"] = keyParams
+	data["HasCompositeKey"] = len(keyParams) > 1
+	data["KeyPath"] = strings.Join(paths, "/")
+	data["KeyWhere"] = strings.Join(conditions, " AND ")
+	data["KeyColumns"] = strings.Join(keyColumns, ", ")
+	data["KeyCache"] = strings.Join(cacheParts, ":")
+	if len(keyParams) == 1 {
+		data["KeyNotWhere"] = fmt.Sprintf("%s <> :%s", keyParams[0]["Column"], keyParams[0]["Name"])
+	} else {
+		data["KeyNotWhere"] = "NOT (" + data["KeyWhere"].(string) + ")"
+	}
+
 	// Convertir foreign keys de database a generator
 	var foreignKeys []ForeignKey
 	for _, dbFk := range table.ForeignKeys {
 		fk := ForeignKey{
-			ColumnName:       dbFk.ColumnName,
-			ReferencedTable:  dbFk.ReferencedTable,
-			ReferencedColumn: dbFk.ReferencedColumn,
-			ConstraintName:   dbFk.ConstraintName,
+			Columns:           dbFk.Columns,
+			ReferencedSchema:  dbFk.ReferencedSchema,
+			ReferencedTable:   dbFk.ReferencedTable,
+			ReferencedColumns: dbFk.ReferencedColumns,
+			ConstraintName:    dbFk.ConstraintName,
 		}
 		foreignKeys = append(foreignKeys, fk)
 	}
 	data["ForeignKeys"] = foreignKeys
 
+	// Restricciones únicas (sin PK) para validar duplicados en new/update
+	data["UniqueKeys"] = g.uniqueKeys(table, fields)
+
+	// Campos del listado por los que se puede ordenar y filtrar
+	var indexedListFields []map[string]interface{}
+	for _, f := range listFields {
+		if f["IsIndexed"].(bool) {
+			indexedListFields = append(indexedListFields, f)
+		}
+	}
+	data["IndexedListFields"] = indexedListFields
+
 	// Determinar si tiene campos de auditoría
 	data["HasAuditFields"] = g.hasAuditFields(table.Columns)
 	data["HasSoftDelete"] = g.hasSoftDeleteField(table.Columns)
@@ -200,6 +397,85 @@ func (g *Generator) prepareTemplateData(table database.Table) map[string]interfa
 	return data
 }
 
+// uniqueKeys returns the unique indexes whose uniqueness is not already
+// guaranteed by the primary key, skipping those on columns the table doesn't
+// expose.
+func (g *Generator) uniqueKeys(table database.Table, fields []map[string]interface{}) []UniqueKey {
+	byName := make(map[string]map[string]interface{}, len(fields))
+	for _, f := range fields {
+		byName[strings.ToLower(f["Name"].(string))] = f
+	}
+
+	var keys []UniqueKey
+	for _, idx := range table.Indexes {
+		if !idx.Unique || idx.Primary {
+			continue
+		}
+		key := UniqueKey{Name: idx.Name}
+		for _, c := range idx.Columns {
+			f, ok := byName[strings.ToLower(c)]
+			if !ok || g.isPrimaryKey(c, table.PrimaryKeys) {
+				key.Fields = nil
+				break
+			}
+			key.Fields = append(key.Fields, f)
+		}
+		if len(key.Fields) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// keyParams returns the path parameters that identify a row, with the
+// column they match and their type. A single-column primary key is always
+// the "id" parameter; a composite one takes a parameter per column, named
+// after it. Tables without primary key are assumed to have an id.
+func (g *Generator) keyParams(table database.Table, fields []map[string]interface{}) []map[string]interface{} {
+	if len(table.PrimaryKeys) == 0 {
+		return []map[string]interface{}{{"Name": "id", "Column": "id", "Type": "int"}}
+	}
+
+	var params []map[string]interface{}
+	for _, pk := range table.PrimaryKeys {
+		fieldType := "string"
+		for _, f := range fields {
+			if strings.EqualFold(f["Name"].(string), pk) {
+				fieldType = f["Type"].(string)
+				break
+			}
+		}
+		params = append(params, map[string]interface{}{
+			"Name":   g.keyParamName(table, pk),
+			"Column": pk,
+			"Type":   fieldType,
+		})
+	}
+	return params
+}
+
+// keyParamName is the parameter that carries a column of the table in its
+// get/update/delete routes (see keyParams), or the column itself for the
+// columns outside the primary key.
+func (g *Generator) keyParamName(table database.Table, column string) string {
+	if len(table.PrimaryKeys) == 1 && strings.EqualFold(table.PrimaryKeys[0], column) {
+		return "id"
+	}
+	return toSnakeCase(column)
+}
+
+// qualifiedName is the name of a table in the generated SQL. ProjectSchema
+// holds the schemas of the connection: with a single one its tables keep the
+// bare name (resolved by the search_path), otherwise, and for tables of any
+// other schema, the name is qualified ("security.users").
+func (g *Generator) qualifiedName(schema, name string) string {
+	schemas := database.SplitSchemas(g.config.ProjectSchema)
+	if schema == "" || (len(schemas) == 1 && strings.EqualFold(schemas[0], schema)) {
+		return name
+	}
+	return schema + "." + name
+}
+
 // Asegurarse de que estas funciones estén definidas
 func (g *Generator) isPrimaryKey(columnName string, primaryKeys []string) bool {
 	for _, pk := range primaryKeys {
@@ -238,7 +514,7 @@ func (g *Generator) hasSoftDeleteField(columns []database.Column) bool {
 
 func (g *Generator) isForeignKey(columnName string, foreignKeys []database.ForeignKey) bool {
 	for _, fk := range foreignKeys {
-		if strings.EqualFold(fk.ColumnName, columnName) {
+		if fk.HasColumn(columnName) {
 			return true
 		}
 	}
@@ -270,6 +546,10 @@ func (g *Generator) prepareIncludes(table database.Table) []map[string]interface
 	// 1. Relaciones Salientes (BelongsTo - 1:1)
 	for _, fk := range table.ForeignKeys {
 		refTable := strings.ToLower(fk.ReferencedTable)
+		refSchema := fk.ReferencedSchema
+		if refSchema == "" {
+			refSchema = table.Schema
+		}
 		// Usually we use singular for object relations
 		relation := singularize(refTable)
 
@@ -277,13 +557,19 @@ func (g *Generator) prepareIncludes(table database.Table) []map[string]interface
 			continue
 		}
 
+		// The referenced row is matched on every column of the key
+		var conditions []string
+		for k, column := range fk.Columns {
+			conditions = append(conditions, fmt.Sprintf("%s = :%s", fk.ReferencedColumns[k], column))
+		}
+
 		include := map[string]interface{}{
 			"Relation":         relation,
-			"ForeignKey":       fk.ColumnName,
+			"ForeignKey":       strings.Join(fk.Columns, ", "),
 			"ReferencedTable":  fk.ReferencedTable,
-			"ReferencedColumn": fk.ReferencedColumn,
+			"ReferencedColumn": strings.Join(fk.ReferencedColumns, ", "),
 			"Type":             "object",
-			"Query":            fmt.Sprintf(`SELECT * FROM %s WHERE %s = :%s`, fk.ReferencedTable, fk.ReferencedColumn, fk.ColumnName),
+			"Query":            fmt.Sprintf(`SELECT * FROM %s WHERE %s`, g.qualifiedName(refSchema, fk.ReferencedTable), strings.Join(conditions, " AND ")),
 		}
 		includes = append(includes, include)
 		existingRelations[relation] = true
@@ -292,15 +578,24 @@ func (g *Generator) prepareIncludes(table database.Table) []map[string]interface
 	// 2. Relaciones Entrantes (HasMany - 1:N) y Join Tables (Many-to-Many - N:M)
 	// Iterate through all other tables to find those that point to the current table
 	for _, otherTable := range g.allTables {
-		if strings.EqualFold(otherTable.Name, table.Name) {
+		if database.TableKey(otherTable.Schema, otherTable.Name) == database.TableKey(table.Schema, table.Name) {
 			continue
 		}
 
-		// Find FKs in otherTable that point to my table (table.Name)
+		// Find FKs in otherTable that point to my table, in its schema
 		for _, fk := range otherTable.ForeignKeys {
-			if strings.EqualFold(fk.ReferencedTable, table.Name) {
+			if fk.References(table.Schema, table.Name) {
 				// Found incoming reference (otherTable -> table)
 
+				// The columns of otherTable are matched with the parameters
+				// that identify my row (":id" for a single primary key)
+				var conditions, joinConditions []string
+				for k, column := range fk.Columns {
+					param := g.keyParamName(table, fk.ReferencedColumns[k])
+					conditions = append(conditions, fmt.Sprintf("%s = :%s", column, param))
+					joinConditions = append(joinConditions, fmt.Sprintf("jt.%s = :%s", column, param))
+				}
+
 				// Case A: Direct 1:N Relation (e.g. User -> Posts)
 				// otherTable (Posts) has user_id.
 				// We add "posts" to User.
@@ -319,11 +614,11 @@ func (g *Generator) prepareIncludes(table database.Table) []map[string]interface
 				if !existingRelations[directRelationName] {
 					include := map[string]interface{}{
 						"Relation":         directRelationName,
-						"ForeignKey":       "id", // My PK (assuming id)
+						"ForeignKey":       strings.Join(fk.ReferencedColumns, ", "), // My key columns
 						"ReferencedTable":  otherTable.Name,
-						"ReferencedColumn": fk.ColumnName, // The FK column in the other table
+						"ReferencedColumn": strings.Join(fk.Columns, ", "), // The FK columns in the other table
 						"Type":             "array",
-						"Query":            fmt.Sprintf(`SELECT * FROM %s WHERE %s = :id`, otherTable.Name, fk.ColumnName),
+						"Query":            fmt.Sprintf(`SELECT * FROM %s WHERE %s`, g.qualifiedName(otherTable.Schema, otherTable.Name), strings.Join(conditions, " AND ")),
 					}
 					includes = append(includes, include)
 					existingRelations[directRelationName] = true
@@ -334,29 +629,25 @@ func (g *Generator) prepareIncludes(table database.Table) []map[string]interface
 				if isPotentialJoinTable {
 					for _, otherFK := range otherTable.ForeignKeys {
 						// Skip the FK pointing to me
-						if otherFK.ColumnName == fk.ColumnName {
+						if strings.EqualFold(strings.Join(otherFK.Columns, ","), strings.Join(fk.Columns, ",")) {
 							continue
 						}
 
 						// This is the FK to the Target
 						targetTableName := otherFK.ReferencedTable
+						targetSchema := otherFK.ReferencedSchema
+						if targetSchema == "" {
+							targetSchema = otherTable.Schema
+						}
 						// Relation name usually plural of target table
 						targetRelationName := pluralize(strings.ToLower(targetTableName))
 
 						if !existingRelations[targetRelationName] {
-							// Construct N:M Query
-
-							// Find target table PK
-							var targetPK string = "id" // Default
-							// Try to see if we can find the exact PK name if we have the table info
-							// But we are iterating over allTables, so we can find it.
-							for _, t := range g.allTables {
-								if strings.EqualFold(t.Name, targetTableName) {
-									if len(t.PrimaryKeys) > 0 {
-										targetPK = t.PrimaryKeys[0]
-									}
-									break
-								}
+							// Construct N:M Query: the join table pairs its
+							// columns with the key of the target
+							var on []string
+							for k, column := range otherFK.Columns {
+								on = append(on, fmt.Sprintf("t.%s = jt.%s", otherFK.ReferencedColumns[k], column))
 							}
 
 							// Query: SELECT t.* FROM target t JOIN join_table jt ON t.id = jt.target_id WHERE jt.source_id = :id
@@ -364,16 +655,15 @@ func (g *Generator) prepareIncludes(table database.Table) []map[string]interface
 							// Note: User example used SELECT r.id, r.nombre ... FROM roles r.
 							// We will use SELECT t.* for generic scaffold.
 
-							query := fmt.Sprintf(`SELECT t.* FROM %s t JOIN %s jt ON t.%s = jt.%s WHERE jt.%s = :id`,
-								targetTableName,
-								otherTable.Name,
-								targetPK,
-								otherFK.ColumnName,
-								fk.ColumnName)
+							query := fmt.Sprintf(`SELECT t.* FROM %s t JOIN %s jt ON %s WHERE %s`,
+								g.qualifiedName(targetSchema, targetTableName),
+								g.qualifiedName(otherTable.Schema, otherTable.Name),
+								strings.Join(on, " AND "),
+								strings.Join(joinConditions, " AND "))
 
 							include := map[string]interface{}{
 								"Relation":         targetRelationName,
-								"ForeignKey":       "id",
+								"ForeignKey":       strings.Join(fk.ReferencedColumns, ", "),
 								"ReferencedTable":  targetTableName,
 								"ReferencedColumn": "N/A (Many-to-Many)",
 								"Type":             "array",
@@ -430,9 +720,12 @@ auth:
 params:
   body:
     {{- range .Fields}}
-    {{- if and (not .IsPrimaryKey) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at")}}
+    {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at")}}
     - name: "{{.NameSnake}}"
       type: "{{.Type}}"
+      {{- with .Format}}
+      format: "{{.}}"
+      {{- end}}
       required: {{.IsRequired}}
       {{- if .Validation}}
       validation:
@@ -450,10 +743,10 @@ commands:
   # Insertar registro
   - type: exec
     sql: |
-      INSERT INTO {{.TableName}} (
+      INSERT INTO {{.QualifiedName}} (
         {{- $first := true}}
         {{- range .Fields}}
-        {{- if and (not .IsPrimaryKey) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at")}}
+        {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at")}}
         {{- if $first}}{{$first = false}}{{else}},{{end}}
         {{.Name}}
         {{- end}}
@@ -461,7 +754,7 @@ commands:
       ) VALUES (
         {{- $first := true}}
         {{- range .Fields}}
-        {{- if and (not .IsPrimaryKey) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at")}}
+        {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at")}}
         {{- if $first}}{{$first = false}}{{else}},{{end}}
         :{{.NameSnake}}
         {{- end}}
@@ -471,7 +764,7 @@ commands:
 
 		"entidad_update.tpl": `version: "1.0"
 method: PUT
-path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/:id/update"
+path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/{{.KeyPath}}/update"
 description: "Actualizar {{.EntityName}}"
 
 auth:
@@ -480,18 +773,25 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar ID válido"
+    {{- end}}
   
   body:
     {{- range .Fields}}
-    {{- if and (not .IsPrimaryKey) (not (isAuditField .Name)) (ne .Name "created_at") (ne .Name "created_by")}}
+    {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (not (isAuditField .Name)) (ne .Name "created_at") (ne .Name "created_by")}}
     - name: "{{.NameSnake}}"
       type: "{{.Type}}"
+      {{- with .Format}}
+      format: "{{.}}"
+      {{- end}}
       required: false
       {{- if .Validation}}
       validation:
@@ -508,7 +808,7 @@ params:
 commands:
   # Verificar que existe
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}{{if .HasSoftDelete}} AND activo = true{{end}}"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}}{{if .HasSoftDelete}} AND activo = true{{end}}"
     condition: "count = 0"
     on_true:
       action: stop
@@ -518,10 +818,10 @@ commands:
   # Actualizar
   - type: exec
     sql: |
-      UPDATE {{.TableName}} SET
+      UPDATE {{.QualifiedName}} SET
         {{- $first := true}}
         {{- range .Fields}}
-        {{- if and (not .IsPrimaryKey) (not (isAuditField .Name)) (ne .Name "created_at") (ne .Name "created_by")}}
+        {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (not (isAuditField .Name)) (ne .Name "created_at") (ne .Name "created_by")}}
         {{if .NameSnake}} {{if hasField .NameSnake $.Fields}}
           {{if $first}}{{$first = false}}{{else}},{{end}}
           {{.Name}} = :{{.NameSnake}}
@@ -531,7 +831,7 @@ commands:
         {{- if .HasAuditFields}}
         {{if not $first}},{{end}}updated_at = NOW()
         {{- end}}
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      WHERE {{.KeyWhere}}
 
 response:
   success:
@@ -541,11 +841,11 @@ response:
 hooks:
   after:
     - type: cache_invalidate
-      keys: ["{{.EntityNamePlural}}:list:*", "{{.EntityNamePlural}}:{{.id}}"]`,
+      keys: ["{{.EntityNamePlural}}:list:*", "{{.EntityNamePlural}}:{{.KeyCache}}"]`,
 
 		"entidad_delete.tpl": `version: "1.0"
 method: DELETE
-path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/:id/delete"
+path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/{{.KeyPath}}/delete"
 description: "Eliminar {{.EntityName}} {{if .HasSoftDelete}}(soft delete){{else}}(físico){{end}}"
 
 auth:
@@ -554,17 +854,21 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar ID válido"
+    {{- end}}
 
 commands:
   # Verificar que existe
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}}"
     condition: "count = 0"
     on_true:
       action: stop
@@ -574,7 +878,7 @@ commands:
   {{- if .HasSoftDelete}}
   # Verificar si ya está inactivo
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}} AND activo = true"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}} AND activo = true"
     condition: "count = 0"
     on_true:
       action: stop
@@ -584,17 +888,17 @@ commands:
   # Soft delete
   - type: exec
     sql: |
-      UPDATE {{.TableName}} SET 
+      UPDATE {{.QualifiedName}} SET 
         activo = false, 
         deleted_at = NOW(),
         deleted_by = :user_id
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      WHERE {{.KeyWhere}}
   {{- else}}
   # Delete físico
   - type: exec
     sql: |
-      DELETE FROM {{.TableName}} 
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      DELETE FROM {{.QualifiedName}} 
+      WHERE {{.KeyWhere}}
   {{- end}}
 
 response:
@@ -605,7 +909,7 @@ response:
 hooks:
   after:
     - type: cache_invalidate
-      keys: ["{{.EntityNamePlural}}:list:*", "{{.EntityNamePlural}}:{{.id}}"]
+      keys: ["{{.EntityNamePlural}}:list:*", "{{.EntityNamePlural}}:{{.KeyCache}}"]
 
 audit:
   enabled: true`,
@@ -632,11 +936,11 @@ commands:
   - type: query
     sql: |
       SELECT * 
-      FROM {{.TableName}} 
+      FROM {{.QualifiedName}} 
       {{- if .HasSoftDelete}}
       WHERE activo = true 
       {{- end}}
-      ORDER BY {{range .PrimaryKeys}}{{.}}{{break}}{{else}}id{{end}} ASC 
+      ORDER BY {{.KeyColumns}} ASC 
       LIMIT :limit OFFSET :offset
     transform_params:
       offset: "(:page - 1) * :limit"
@@ -653,7 +957,7 @@ commands:
 
 		"entidad_get.tpl": `version: "1.0"
 method: GET
-path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/:id/get"
+path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/{{.KeyPath}}/get"
 description: "Obtener {{.EntityName}} por ID"
 
 auth:
@@ -662,20 +966,24 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar ID válido"
+    {{- end}}
 
 commands:
   # Obtener {{.EntityName}}
   - type: query
     sql: |
       SELECT *
-      FROM {{.TableName}} 
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      FROM {{.QualifiedName}} 
+      WHERE {{.KeyWhere}}
       {{- if .HasSoftDelete}}
       AND activo = true
       {{- end}}
@@ -706,7 +1014,7 @@ response:
 cache:
   enabled: true
   ttl: 300
-  key: "{{.EntityNamePlural}}:{{.id}}"
+  key: "{{.EntityNamePlural}}:{{.KeyCache}}"
 
 audit:
   enabled: true`,
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestDir is the directory, relative to the project rootdir, where the
+// generator keeps its own state. Hidden, so the endpoints browser skips it.
+const ManifestDir = ".apigen"
+
+// Manifest keeps the pristine output of every generated file, i.e. exactly
+// what the templates rendered the last time, under
+// <rootdir>/.apigen/pristine/<relative path>. It is the common ancestor of the
+// three-way merge between the file on disk and a new rendering.
+type Manifest struct {
+	rootDir string
+}
+
+// NewManifest returns the manifest of a project rootdir.
+func NewManifest(rootDir string) *Manifest {
+	return &Manifest{rootDir: rootDir}
+}
+
+// pristinePath maps a generated file to its pristine copy. Files outside the
+// rootdir are not tracked.
+func (m *Manifest) pristinePath(path string) (string, bool) {
+	rel, err := filepath.Rel(m.rootDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.Join(m.rootDir, ManifestDir, "pristine", rel), true
+}
+
+// Pristine returns the last rendered content of a generated file. The second
+// result is false when the file was never generated with a manifest.
+func (m *Manifest) Pristine(path string) (string, bool, error) {
+	pristine, ok := m.pristinePath(path)
+	if !ok {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(pristine)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("error reading pristine copy: %v", err)
+	}
+	return string(content), true, nil
+}
+
+// Record stores the rendered content of a generated file.
+func (m *Manifest) Record(path, content string) error {
+	pristine, ok := m.pristinePath(path)
+	if !ok {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(pristine), 0755); err != nil {
+		return fmt.Errorf("error creating manifest directory: %v", err)
+	}
+	return os.WriteFile(pristine, []byte(content), 0644)
+}
+
+// Forget removes the pristine copy of a generated file, so the next
+// generation replaces the file instead of merging it.
+func (m *Manifest) Forget(path string) error {
+	pristine, ok := m.pristinePath(path)
+	if !ok {
+		return nil
+	}
+	if err := os.Remove(pristine); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing pristine copy: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Protected regions are blocks of a generated file delimited by
+//
+//	# region:name
+//	...
+//	# endregion
+//
+// Templates declare them (usually empty or with a commented example) and the
+// user edits their content by hand. On regeneration the content of every
+// region is carried over from the existing file into the new output.
+var (
+	regionStartRe = regexp.MustCompile(`^\s*#\s*region:([A-Za-z0-9_.-]+)\s*$`)
+	regionEndRe   = regexp.MustCompile(`^\s*#\s*endregion\b`)
+)
+
+// parseRegions returns the body lines of every region of a file by name.
+func parseRegions(content string) (map[string][]string, error) {
+	regions := make(map[string][]string)
+	current := ""
+	var body []string
+
+	for i, line := range strings.Split(content, "\n") {
+		if m := regionStartRe.FindStringSubmatch(line); m != nil {
+			if current != "" {
+				return nil, fmt.Errorf("line %d: region %q opened inside region %q", i+1, m[1], current)
+			}
+			if _, dup := regions[m[1]]; dup {
+				return nil, fmt.Errorf("line %d: duplicate region %q", i+1, m[1])
+			}
+			current, body = m[1], []string{}
+			continue
+		}
+		if regionEndRe.MatchString(line) {
+			if current == "" {
+				return nil, fmt.Errorf("line %d: endregion without region", i+1)
+			}
+			regions[current] = body
+			current = ""
+			continue
+		}
+		if current != "" {
+			body = append(body, line)
+		}
+	}
+
+	if current != "" {
+		return nil, fmt.Errorf("region %q is not closed", current)
+	}
+	return regions, nil
+}
+
+// MergeRegions copies the content of the protected regions of existing into
+// the same-named regions of generated. It returns the merged content and the
+// names of the regions of existing that the new output no longer declares, so
+// their content would be lost.
+func MergeRegions(existing, generated string) (string, []string, error) {
+	kept, err := parseRegions(existing)
+	if err != nil {
+		return "", nil, fmt.Errorf("existing file: %v", err)
+	}
+	if len(kept) == 0 {
+		return generated, nil, nil
+	}
+
+	// Validates the generated markers as well
+	declared, err := parseRegions(generated)
+	if err != nil {
+		return "", nil, fmt.Errorf("generated file: %v", err)
+	}
+
+	var out []string
+	skipping := false
+	for _, line := range strings.Split(generated, "\n") {
+		if m := regionStartRe.FindStringSubmatch(line); m != nil {
+			out = append(out, line)
+			if body, ok := kept[m[1]]; ok {
+				out = append(out, body...)
+				skipping = true
+			}
+			continue
+		}
+		if regionEndRe.MatchString(line) {
+			skipping = false
+		}
+		if !skipping {
+			out = append(out, line)
+		}
+	}
+
+	var dropped []string
+	for name := range kept {
+		if _, ok := declared[name]; !ok {
+			dropped = append(dropped, name)
+		}
+	}
+	sort.Strings(dropped)
+	return strings.Join(out, "\n"), dropped, nil
+}
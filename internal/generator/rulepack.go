@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultRulePacks are the validation rule packs of a project that doesn't
+// select any.
+var DefaultRulePacks = []string{"general", "latam"}
+
+// NoRulePacks is the selection that turns every rule pack off.
+const NoRulePacks = "none"
+
+// rulePackNameRe is what a pack name may hold: it names a file of the packs
+// directory and nothing outside it.
+var rulePackNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// RulePack is a named set of validation rules, read from <dir>/<name>.json.
+type RulePack struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Rules       []ValidationRule `json:"rules"`
+}
+
+// ValidationRule gives a validation map to the fields whose lower-case
+// column name matches Column (a regular expression searched in the name; an
+// empty one matches every column) and whose type category is one of Types
+// (any type when empty). Rules are tried by descending priority and the first
+// that matches wins.
+type ValidationRule struct {
+	Name       string                 `json:"name"`
+	Column     string                 `json:"column"`
+	Types      []string               `json:"types"`
+	Priority   int                    `json:"priority"`
+	Validation map[string]interface{} `json:"validation"`
+
+	re *regexp.Regexp
+}
+
+func (r ValidationRule) matches(colName, category string) bool {
+	if len(r.Types) > 0 {
+		found := false
+		for _, t := range r.Types {
+			if strings.EqualFold(t, category) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.re.MatchString(colName)
+}
+
+// typeCategory is the built-in type of a column without its details
+// ("string", "int", "decimal", "array"). Rules match it rather than the mapped
+// type, so the type mappings of a project don't turn them off.
+func typeCategory(col Column) string {
+	category := templateType(col)
+	if i := strings.IndexAny(category, "(<"); i >= 0 {
+		category = category[:i]
+	}
+	return category
+}
+
+// ReadRulePack reads and checks the rule pack of the given name in dir.
+func ReadRulePack(dir, name string) (RulePack, error) {
+	var pack RulePack
+	if !rulePackNameRe.MatchString(name) {
+		return pack, fmt.Errorf("invalid rule pack name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return pack, fmt.Errorf("rule pack %s: %v", name, err)
+	}
+	// Numbers are kept as written so they print without exponent
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&pack); err != nil {
+		return pack, fmt.Errorf("rule pack %s: %v", name, err)
+	}
+	if pack.Name == "" {
+		pack.Name = name
+	}
+	for i := range pack.Rules {
+		rule := &pack.Rules[i]
+		re, err := regexp.Compile(rule.Column)
+		if err != nil {
+			return pack, fmt.Errorf("rule pack %s, rule %s: invalid column pattern: %v", name, rule.Name, err)
+		}
+		rule.re = re
+	}
+	return pack, nil
+}
+
+// ListRulePacks returns the rule packs available in dir, by name.
+func ListRulePacks(dir string) ([]RulePack, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var packs []RulePack
+	for _, file := range files {
+		pack, err := ReadRulePack(dir, strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Name < packs[j].Name })
+	return packs, nil
+}
+
+// LoadValidationRules reads the named packs of dir and returns their rules by
+// descending priority; rules of the same priority keep the order of the packs
+// and of the pack.
+func LoadValidationRules(dir string, names []string) ([]ValidationRule, error) {
+	var rules []ValidationRule
+	for _, name := range names {
+		pack, err := ReadRulePack(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, pack.Rules...)
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority > rules[j].Priority })
+	return rules, nil
+}
+
+// SelectedRulePacks returns the packs of the selection stored for a project:
+// DefaultRulePacks when it is empty, none for NoRulePacks, else its names.
+func SelectedRulePacks(list string) []string {
+	names := SplitRulePacks(list)
+	switch {
+	case len(names) == 0:
+		return DefaultRulePacks
+	case len(names) == 1 && names[0] == NoRulePacks:
+		return nil
+	}
+	return names
+}
+
+// SplitRulePacks reads a list of rule pack names separated by commas or
+// spaces, lower-cased and with duplicates dropped.
+func SplitRulePacks(list string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			names = append(names, key)
+		}
+	}
+	return names
+}
@@ -3,12 +3,15 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
+
+	"api-scaffolding/internal/backup"
 )
 
 // Definir tipos necesarios
@@ -21,13 +24,32 @@ type Column struct {
 	DefaultValue *string
 	MaxLength    *int
 	Comment      string
+	Position     int
+	IsIdentity   bool
+	IsGenerated  bool
+	Enum         []string
+	Min          *float64
+	Max          *float64
+	Pattern      string
+	ElementType  string
+	Domain       string
+	Precision    *int
+	Scale        *int
 }
 
 type ForeignKey struct {
-	ColumnName       string
-	ReferencedTable  string
-	ReferencedColumn string
-	ConstraintName   string
+	Columns           []string
+	ReferencedSchema  string
+	ReferencedTable   string
+	ReferencedColumns []string
+	ConstraintName    string
+}
+
+// UniqueKey is a unique constraint or index other than the primary key, with
+// the template fields of its columns.
+type UniqueKey struct {
+	Name   string
+	Fields []map[string]interface{}
 }
 
 type TemplateProcessor struct {
@@ -47,6 +69,7 @@ func NewTemplateProcessor(templatesDir string) (*TemplateProcessor, error) {
 			"pluralize":             pluralize,
 			"singularize":           singularize,
 			"formatType":            formatType,
+			"templateType":          templateType,
 			"getValidation":         getValidation,
 			"getDefault":            getDefault,
 			"getDefaultValue":       getDefaultValue,
@@ -60,6 +83,7 @@ func NewTemplateProcessor(templatesDir string) (*TemplateProcessor, error) {
 			"split":                 strings.Split,
 			"quote":                 strconv.Quote,
 			"toString":              toString,
+			"yamlEscape":            yamlEscape,
 			"hasField":              hasField,
 			"isAuditField":          isAuditField,
 			"shouldIncludeInUpdate": shouldIncludeInUpdate,
@@ -139,9 +163,12 @@ auth:
 params:
   body:
     {{- range .Fields}}
-    {{- if and (not .IsPrimaryKey) (not (isAuditField .Name))}}
+    {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (not (isAuditField .Name))}}
     - name: "{{.NameSnake}}"
       type: "{{.Type}}"
+      {{- with .Format}}
+      format: "{{.}}"
+      {{- end}}
       required: {{.IsRequired}}
       {{- if .Validation}}
       validation:
@@ -163,7 +190,7 @@ commands:
   {{- range .Fields}}
   {{- if and (not .IsPrimaryKey) (contains (toLowerCase .Name) "email")}}
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{$.TableName}} WHERE {{.Name}} = :{{.NameSnake}}"
+    sql: "SELECT COUNT(*) as count FROM {{$.QualifiedName}} WHERE {{.Name}} = :{{.NameSnake}}"
     condition: "count > 0"
     on_true:
       action: stop
@@ -171,7 +198,7 @@ commands:
       message: "Ya existe un registro con ese {{.Name}}"
   {{- else if and (not .IsPrimaryKey) (contains (toLowerCase .Name) "username")}}
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{$.TableName}} WHERE {{.Name}} = :{{.NameSnake}}"
+    sql: "SELECT COUNT(*) as count FROM {{$.QualifiedName}} WHERE {{.Name}} = :{{.NameSnake}}"
     condition: "count > 0"
     on_true:
       action: stop
@@ -183,10 +210,10 @@ commands:
   # Insertar {{.EntityName}}
   - type: exec
     sql: |
-      INSERT INTO {{.TableName}} (
+      INSERT INTO {{.QualifiedName}} (
         {{- $fields := list}}
         {{- range .Fields}}
-        {{- if and (not .IsPrimaryKey) (not (isAuditField .Name))}}
+        {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (not (isAuditField .Name))}}
         {{- $fields = append $fields .Name}}
         {{- end}}
         {{- end}}
@@ -196,7 +223,7 @@ commands:
       ) VALUES (
         {{- $first := true}}
         {{- range .Fields}}
-        {{- if and (not .IsPrimaryKey) (not (isAuditField .Name))}}
+        {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (not (isAuditField .Name))}}
         {{- if $first}}{{$first = false}}{{else}},{{end}}
         :{{.NameSnake}}
         {{- end}}
@@ -228,7 +255,7 @@ audit:
 
 		"entidad_update.tpl": `version: "1.0"
 method: PUT
-path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/:id/update"
+path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/{{.KeyPath}}/update"
 description: "Actualizar {{.EntityName}}"
 
 auth:
@@ -237,18 +264,25 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar ID válido"
+    {{- end}}
   
   body:
     {{- range .Fields}}
-    {{- if and (not .IsPrimaryKey) (not (isAuditField .Name)) (ne .Name "created_at") (ne .Name "created_by")}}
+    {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (not (isAuditField .Name)) (ne .Name "created_at") (ne .Name "created_by")}}
     - name: "{{.NameSnake}}"
       type: "{{.Type}}"
+      {{- with .Format}}
+      format: "{{.}}"
+      {{- end}}
       required: false
       {{- if .Validation}}
       validation:
@@ -265,7 +299,7 @@ params:
 commands:
   # Verificar que existe
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}{{if .HasSoftDelete}} AND activo = true{{end}}"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}}{{if .HasSoftDelete}} AND activo = true{{end}}"
     condition: "count = 0"
     on_true:
       action: stop
@@ -275,10 +309,10 @@ commands:
   # Actualizar
   - type: exec
     sql: |
-      UPDATE {{.TableName}} SET
+      UPDATE {{.QualifiedName}} SET
         {{- $first := true}}
         {{- range .Fields}}
-        {{- if and (not .IsPrimaryKey) (not (isAuditField .Name)) (ne .Name "created_at") (ne .Name "created_by")}}
+        {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (not (isAuditField .Name)) (ne .Name "created_at") (ne .Name "created_by")}}
         {{if .NameSnake}} {{if hasField .NameSnake $.Fields}}
           {{if $first}}{{$first = false}}{{else}},{{end}}
           {{.Name}} = :{{.NameSnake}}
@@ -288,7 +322,7 @@ commands:
         {{- if .HasAuditFields}}
         {{if not $first}},{{end}}updated_at = NOW()
         {{- end}}
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      WHERE {{.KeyWhere}}
 
 response:
   success:
@@ -298,11 +332,11 @@ response:
 hooks:
   after:
     - type: cache_invalidate
-      keys: ["{{.EntityNamePlural}}:list:*", "{{.EntityNamePlural}}:{{.id}}"]`,
+      keys: ["{{.EntityNamePlural}}:list:*", "{{.EntityNamePlural}}:{{.KeyCache}}"]`,
 
 		"entidad_delete.tpl": `version: "1.0"
 method: DELETE
-path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/:id/delete"
+path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/{{.KeyPath}}/delete"
 description: "Eliminar {{.EntityName}} {{if .HasSoftDelete}}(soft delete){{else}}(físico){{end}}"
 
 auth:
@@ -311,17 +345,21 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar ID válido"
+    {{- end}}
 
 commands:
   # Verificar que existe
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}}"
     condition: "count = 0"
     on_true:
       action: stop
@@ -331,7 +369,7 @@ commands:
   {{- if .HasSoftDelete}}
   # Verificar si ya está inactivo
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}} AND activo = true"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}} AND activo = true"
     condition: "count = 0"
     on_true:
       action: stop
@@ -341,17 +379,17 @@ commands:
   # Soft delete
   - type: exec
     sql: |
-      UPDATE {{.TableName}} SET 
+      UPDATE {{.QualifiedName}} SET 
         activo = false, 
         deleted_at = NOW(),
         deleted_by = :user_id
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      WHERE {{.KeyWhere}}
   {{- else}}
   # Delete físico
   - type: exec
     sql: |
-      DELETE FROM {{.TableName}} 
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      DELETE FROM {{.QualifiedName}} 
+      WHERE {{.KeyWhere}}
   {{- end}}
 
 response:
@@ -362,7 +400,7 @@ response:
 hooks:
   after:
     - type: cache_invalidate
-      keys: ["{{.EntityNamePlural}}:list:*", "{{.EntityNamePlural}}:{{.id}}"]
+      keys: ["{{.EntityNamePlural}}:list:*", "{{.EntityNamePlural}}:{{.KeyCache}}"]
 
 audit:
   enabled: true`,
@@ -395,7 +433,7 @@ params:
     - name: orden
       type: string
       required: false
-      default: "{{range .PrimaryKeys}}{{.}}{{break}}{{else}}id{{end}}"
+      default: "{{range $i, $pk := .PrimaryKeys}}{{if $i}},{{end}}{{$pk}}{{else}}id{{end}}"
       validation:
         pattern: "^[a-zA-Z_,]+$"
 
@@ -403,7 +441,7 @@ commands:
   - type: query
     sql: |
       SELECT *
-      FROM {{.TableName}} 
+      FROM {{.QualifiedName}} 
       {{- if .HasSoftDelete}}
       WHERE activo = true 
         {{- if .search}}
@@ -438,7 +476,7 @@ response:
     pagination:
       total_query: |
         SELECT COUNT(*) 
-        FROM {{.TableName}} 
+        FROM {{.QualifiedName}} 
         {{- if .HasSoftDelete}}
         WHERE activo = true 
           {{- if .search}}
@@ -470,7 +508,7 @@ cache:
 
 		"entidad_get.tpl": `version: "1.0"
 method: GET
-path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/:id/get"
+path: "/{{.SubsystemLower}}/{{.EntityNamePlural}}/{{.KeyPath}}/get"
 description: "Obtener {{.EntityName}} por ID"
 
 auth:
@@ -479,20 +517,24 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar ID válido"
+    {{- end}}
 
 commands:
   # Obtener {{.EntityName}}
   - type: query
     sql: |
       SELECT *
-      FROM {{.TableName}} 
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      FROM {{.QualifiedName}} 
+      WHERE {{.KeyWhere}}
       {{- if .HasSoftDelete}}
       AND activo = true
       {{- end}}
@@ -515,9 +557,7 @@ response:
     {{- range .Includes}}
     - relation: {{.Relation}}
       query: |
-        SELECT * 
-        FROM {{.ReferencedTable}} 
-        WHERE {{.ReferencedColumn}} = :{{.ForeignKey}}
+        {{.Query}}
       type: {{.Type}}
     {{- end}}
   {{- end}}
@@ -525,7 +565,7 @@ response:
 cache:
   enabled: true
   ttl: 300
-  key: "{{.EntityNamePlural}}:{{.id}}"
+  key: "{{.EntityNamePlural}}:{{.KeyCache}}"
 
 audit:
   enabled: true`,
@@ -555,7 +595,9 @@ func (tp *TemplateProcessor) Process(templateName string, data interface{}) (str
 	return buf.String(), nil
 }
 
-func (tp *TemplateProcessor) ProcessToFile(templateName string, data interface{}, outputPath string) error {
+// ProcessToFile renders a template into outputPath, moving the existing file
+// to the backups of the project first.
+func (tp *TemplateProcessor) ProcessToFile(templateName string, data interface{}, outputPath string, backups *backup.Store) error {
 	content, err := tp.Process(templateName, data)
 	if err != nil {
 		return err
@@ -566,12 +608,9 @@ func (tp *TemplateProcessor) ProcessToFile(templateName string, data interface{}
 		return fmt.Errorf("error creating directory: %v", err)
 	}
 
-	// Renombrar archivo existente si existe
-	if _, err := os.Stat(outputPath); err == nil {
-		backupPath := fmt.Sprintf("%s.%s.bak", outputPath, time.Now().Format("20060102"))
-		if err := os.Rename(outputPath, backupPath); err != nil {
-			return fmt.Errorf("error backing up existing file: %v", err)
-		}
+	// Mover el archivo existente a los backups
+	if _, err := backups.Backup(outputPath); err != nil {
+		return fmt.Errorf("error backing up existing file: %v", err)
 	}
 
 	// Escribir archivo
@@ -647,6 +686,9 @@ func singularize(s string) string {
 func formatType(dbType string) string {
 	dbType = strings.ToLower(dbType)
 	switch {
+	case dbType == "interval" || strings.Contains(dbType, "point"):
+		// Not integers despite the "int" in the name
+		return "string"
 	case strings.Contains(dbType, "int"):
 		if strings.Contains(dbType, "big") {
 			return "int64"
@@ -654,7 +696,7 @@ func formatType(dbType string) string {
 		return "int"
 	case strings.Contains(dbType, "bool"):
 		return "bool"
-	case strings.Contains(dbType, "float") || strings.Contains(dbType, "double") || strings.Contains(dbType, "decimal") || strings.Contains(dbType, "numeric"):
+	case strings.Contains(dbType, "float") || strings.Contains(dbType, "double") || strings.Contains(dbType, "decimal") || strings.Contains(dbType, "numeric") || strings.Contains(dbType, "real"):
 		return "float"
 	case strings.Contains(dbType, "timestamp") || strings.Contains(dbType, "datetime") || strings.Contains(dbType, "date"):
 		return "string"
@@ -667,11 +709,56 @@ func formatType(dbType string) string {
 	}
 }
 
+// templateType is the type of a field in the templates: formatType of the
+// column type, "array<int>" for arrays and "decimal(12,2)" for numerics with
+// a declared precision. Domains take the type of their base type.
+func templateType(col Column) string {
+	switch {
+	case col.ElementType != "":
+		return "array<" + formatType(col.ElementType) + ">"
+	case col.Precision != nil && isDecimalType(col.DataType):
+		scale := 0
+		if col.Scale != nil {
+			scale = *col.Scale
+		}
+		return fmt.Sprintf("decimal(%d,%d)", *col.Precision, scale)
+	}
+	return formatType(col.DataType)
+}
+
+func isDecimalType(dbType string) bool {
+	dbType = strings.ToLower(dbType)
+	return dbType == "numeric" || dbType == "decimal"
+}
+
+// decimalBound is the largest value of a decimal(precision, scale), written
+// out so that it prints without exponent: 9999999999.99 for decimal(12,2).
+func decimalBound(precision, scale int) string {
+	if scale >= precision {
+		return "0." + strings.Repeat("9", precision)
+	}
+	bound := strings.Repeat("9", precision-scale)
+	if scale > 0 {
+		bound += "." + strings.Repeat("9", scale)
+	}
+	return bound
+}
+
+// getValidation returns the validations deduced from the column type and the
+// database rules; the name rules of the rule packs need the generator.
 func getValidation(col Column, isRequired bool) map[string]interface{} {
+	return validationFor(col, isRequired, typeMapping{Type: templateType(col)}, nil)
+}
+
+// validationFor builds the validations of a field of the given mapped type:
+// those of its type, then the first of the validation rules that matches its
+// name. The default validations of its type mapping override them and the
+// rules of the database override everything.
+func validationFor(col Column, isRequired bool, mapping typeMapping, rules []ValidationRule) map[string]interface{} {
 	validation := make(map[string]interface{})
 
 	colName := strings.ToLower(col.Name)
-	fieldType := formatType(col.DataType)
+	fieldType := mapping.Type
 
 	// Validaciones por tipo de dato
 	switch fieldType {
@@ -681,257 +768,10 @@ func getValidation(col Column, isRequired bool) map[string]interface{} {
 			validation["min_length"] = 2
 			validation["max_length"] = *col.MaxLength
 		}
-		colName = strings.ToLower(colName)
-		// =====================
-		// EMAIL
-		// =====================
-		if strings.Contains(colName, "email") || strings.Contains(colName, "correo") {
-			validation["email"] = true
-			// =====================
-			// URL / WEB
-			// =====================
-		} else if strings.Contains(colName, "url") || strings.Contains(colName, "website") || strings.Contains(colName, "sitio") {
-			validation["pattern"] = `^https?:\/\/[^\s/$.?#].[^\s]*$`
-			// =====================
-			// TELÉFONO
-			// =====================
-		} else if strings.Contains(colName, "telefono") || strings.Contains(colName, "phone") || strings.Contains(colName, "celular") || strings.Contains(colName, "movil") {
-			validation["pattern"] = `^[0-9+\-\s\(\)]{7,20}$`
-			// =====================
-			// CUIT / CUIL (Argentina)
-			// =====================
-		} else if strings.Contains(colName, "cuit") {
-			validation["pattern"] = `^(20|23|24|27|30|33|34)-?\d{8}-?\d$`
-		} else if strings.Contains(colName, "cuil") {
-			validation["pattern"] = `^(20|23|24|27)-?\d{8}-?\d$`
-			// =====================
-			// CBU (Argentina)
-			// =====================
-		} else if strings.Contains(colName, "cbu") {
-			validation["pattern"] = `^\d{22}$`
-			// =====================
-			// CVU / ALIAS (Argentina)
-			// =====================
-		} else if strings.Contains(colName, "cvu") {
-			validation["pattern"] = `^\d{22}$`
-		} else if strings.Contains(colName, "alias") && (strings.Contains(colName, "bancario") || strings.Contains(colName, "cvu")) {
-			validation["pattern"] = `^[a-z0-9.]{6,20}$`
-			// =====================
-			// DNI
-			// =====================
-		} else if colName == "dni" || strings.Contains(colName, "documento") {
-			validation["pattern"] = `^\d{7,8}$`
-			// =====================
-			// PASAPORTE
-			// =====================
-		} else if strings.Contains(colName, "pasaporte") || strings.Contains(colName, "passport") {
-			validation["pattern"] = `^[A-Z]{3}\d{6}$`
-			// =====================
-			// RUC (Perú, Paraguay)
-			// =====================
-		} else if strings.Contains(colName, "ruc") {
-			validation["pattern"] = `^\d{11}$`
-			// =====================
-			// RFC (México)
-			// =====================
-		} else if strings.Contains(colName, "rfc") {
-			validation["pattern"] = `^[A-ZÑ&]{3,4}\d{6}[A-Z0-9]{3}$`
-			// =====================
-			// CURP (México)
-			// =====================
-		} else if strings.Contains(colName, "curp") {
-			validation["pattern"] = `^[A-Z]{4}\d{6}[HM][A-Z]{5}[0-9A-Z]\d$`
-			// =====================
-			// CPF (Brasil)
-			// =====================
-		} else if strings.Contains(colName, "cpf") {
-			validation["pattern"] = `^\d{3}\.\d{3}\.\d{3}-\d{2}$|^\d{11}$`
-			// =====================
-			// CNPJ (Brasil)
-			// =====================
-		} else if strings.Contains(colName, "cnpj") {
-			validation["pattern"] = `^\d{2}\.\d{3}\.\d{3}\/\d{4}-\d{2}$|^\d{14}$`
-			// =====================
-			// RUT (Chile)
-			// =====================
-		} else if strings.Contains(colName, "rut") {
-			validation["pattern"] = `^\d{1,2}\.\d{3}\.\d{3}-[\dkK]$|^\d{7,8}-[\dkK]$`
-			// =====================
-			// CÓDIGO POSTAL
-			// =====================
-		} else if strings.Contains(colName, "codigo_postal") || strings.Contains(colName, "postal") || strings.Contains(colName, "zip") {
-			validation["pattern"] = `^([A-Z]\d{4}[A-Z]{3}|\d{4,5})$`
-			// =====================
-			// USERNAME
-			// =====================
-		} else if strings.Contains(colName, "username") || strings.Contains(colName, "usuario") || strings.Contains(colName, "login") {
-			validation["pattern"] = `^[a-zA-Z0-9._-]{4,20}$`
-			// =====================
-			// NOMBRE / APELLIDO
-			// =====================
-		} else if strings.Contains(colName, "nombre") || strings.Contains(colName, "apellido") || strings.Contains(colName, "name") {
-			validation["pattern"] = `^[\p{L}\s]+$`
-			//validation["pattern"] = `^[A-Za-zÁÉÍÓÚáéíóúÑñÃãÕõÇçÊêÔôÂâÀà\s]+$`
-			// =====================
-			// PASSWORD
-			// =====================
-		} else if strings.Contains(colName, "password") || strings.Contains(colName, "clave") || strings.Contains(colName, "contrasena") {
-			validation["pattern"] = `^[A-Za-z0-9@$!%*?&#+._-]{8,}$`
-			// =====================
-			// UUID
-			// =====================
-		} else if strings.Contains(colName, "uuid") || strings.Contains(colName, "guid") {
-			validation["pattern"] = `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[1-5][0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`
-			// =====================
-			// IP v4
-			// =====================
-		} else if strings.Contains(colName, "ip") && !strings.Contains(colName, "v6") {
-			validation["pattern"] = `^(25[0-5]|2[0-4]\d|[01]?\d\d?)\.((25[0-5]|2[0-4]\d|[01]?\d\d?)\.){2}(25[0-5]|2[0-4]\d|[01]?\d\d?)$`
-			// =====================
-			// IP v6
-			// =====================
-		} else if strings.Contains(colName, "ipv6") {
-			validation["pattern"] = `^(([0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}|::([0-9a-fA-F]{1,4}:){0,6}[0-9a-fA-F]{1,4})$`
-			// =====================
-			// MAC ADDRESS
-			// =====================
-		} else if strings.Contains(colName, "mac") && strings.Contains(colName, "address") {
-			validation["pattern"] = `^([0-9A-Fa-f]{2}[:-]){5}([0-9A-Fa-f]{2})$`
-			// =====================
-			// PATENTE ARGENTINA
-			// =====================
-		} else if strings.Contains(colName, "patente") || strings.Contains(colName, "dominio") {
-			validation["pattern"] = `^([A-Z]{3}\d{3}|[A-Z]{2}\d{3}[A-Z]{2})$`
-			// =====================
-			// VIN (Vehicle Identification Number)
-			// =====================
-		} else if strings.Contains(colName, "vin") || strings.Contains(colName, "chasis") {
-			validation["pattern"] = `^[A-HJ-NPR-Z0-9]{17}$`
-			// =====================
-			// COLOR HEX
-			// =====================
-		} else if strings.Contains(colName, "color") && !strings.Contains(colName, "nombre") {
-			validation["pattern"] = `^#([A-Fa-f0-9]{6}|[A-Fa-f0-9]{3})$`
-			// =====================
-			// TARJETA DE CRÉDITO
-			// =====================
-		} else if strings.Contains(colName, "tarjeta") || strings.Contains(colName, "card") {
-			validation["pattern"] = `^\d{13,19}$`
-			// =====================
-			// CVV
-			// =====================
-		} else if strings.Contains(colName, "cvv") || strings.Contains(colName, "cvc") {
-			validation["pattern"] = `^\d{3,4}$`
-			// =====================
-			// IBAN (Internacional)
-			// =====================
-		} else if strings.Contains(colName, "iban") {
-			validation["pattern"] = `^[A-Z]{2}\d{2}[A-Z0-9]{1,30}$`
-			// =====================
-			// SWIFT/BIC
-			// =====================
-		} else if strings.Contains(colName, "swift") || strings.Contains(colName, "bic") {
-			validation["pattern"] = `^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`
-			// =====================
-			// ISBN (10 o 13 dígitos)
-			// =====================
-		} else if strings.Contains(colName, "isbn") {
-			validation["pattern"] = `^(97[89])?\d{9}[\dX]$`
-			// =====================
-			// SLUG (URL friendly)
-			// =====================
-		} else if strings.Contains(colName, "slug") {
-			validation["pattern"] = `^[a-z0-9]+(?:-[a-z0-9]+)*$`
-			// =====================
-			// COORDENADAS (Latitud)
-			// =====================
-		} else if strings.Contains(colName, "latitud") || strings.Contains(colName, "latitude") || colName == "lat" {
-			validation["pattern"] = `^-?([0-8]?\d(\.\d+)?|90(\.0+)?)$`
-			// =====================
-			// COORDENADAS (Longitud)
-			// =====================
-		} else if strings.Contains(colName, "longitud") || strings.Contains(colName, "longitude") || colName == "lng" || colName == "lon" {
-			validation["pattern"] = `^-?(1[0-7]\d(\.\d+)?|180(\.0+)?|\d{1,2}(\.\d+)?)$`
-			// =====================
-			// HASHTAG
-			// =====================
-		} else if strings.Contains(colName, "hashtag") || strings.Contains(colName, "tag") {
-			validation["pattern"] = `^#[A-Za-z0-9_]+$`
-			// =====================
-			// HANDLE SOCIAL (@usuario)
-			// =====================
-		} else if strings.Contains(colName, "handle") || strings.Contains(colName, "twitter") || strings.Contains(colName, "instagram") {
-			validation["pattern"] = `^@?[A-Za-z0-9_]{1,15}$`
-			// =====================
-			// VERSIÓN SEMÁNTICA
-			// =====================
-		} else if strings.Contains(colName, "version") {
-			validation["pattern"] = `^\d+\.\d+\.\d+(-[a-zA-Z0-9]+)?$`
-			// =====================
-			// MONEDA (código ISO)
-			// =====================
-		} else if strings.Contains(colName, "moneda") || strings.Contains(colName, "currency") {
-			validation["pattern"] = `^[A-Z]{3}$`
-			// =====================
-			// CÓDIGO DE IDIOMA (ISO 639-1)
-			// =====================
-		} else if strings.Contains(colName, "idioma") || strings.Contains(colName, "language") || strings.Contains(colName, "lang") {
-			validation["pattern"] = `^[a-z]{2}(-[A-Z]{2})?$`
-			// =====================
-			// CÓDIGO DE PAÍS (ISO 3166-1 alpha-2)
-			// =====================
-		} else if strings.Contains(colName, "pais") || strings.Contains(colName, "country") {
-			validation["pattern"] = `^[A-Z]{2}$`
-			// =====================
-			// HORA (HH:MM o HH:MM:SS)
-			// =====================
-		} else if strings.Contains(colName, "hora") || strings.Contains(colName, "time") {
-			validation["pattern"] = `^([01]\d|2[0-3]):[0-5]\d(:[0-5]\d)?$`
-			// =====================
-			// NÚMERO DE ORDEN / FACTURA
-			// =====================
-		} else if strings.Contains(colName, "numero") && (strings.Contains(colName, "orden") || strings.Contains(colName, "factura") || strings.Contains(colName, "invoice")) {
-			validation["pattern"] = `^[A-Z0-9]{5,20}$`
-			// =====================
-			// MATRÍCULA (estudiantes)
-			// =====================
-		} else if strings.Contains(colName, "matricula") || strings.Contains(colName, "legajo") {
-			validation["pattern"] = `^[A-Z0-9]{5,15}$`
-			// =====================
-			// CÓDIGO DE BARRAS (EAN-13)
-			// =====================
-		} else if strings.Contains(colName, "codigo_barras") || strings.Contains(colName, "ean") || strings.Contains(colName, "barcode") {
-			validation["pattern"] = `^\d{13}$`
-			// =====================
-			// NÚMERO DE LOTE
-			// =====================
-		} else if strings.Contains(colName, "lote") || strings.Contains(colName, "batch") {
-			validation["pattern"] = `^[A-Z0-9]{6,20}$`
-			// =====================
-			// EXTENSIÓN DE ARCHIVO
-			// =====================
-		} else if strings.Contains(colName, "extension") {
-			validation["pattern"] = `^\.[a-z0-9]{2,5}$`
-			// =====================
-			// MIME TYPE
-			// =====================
-		} else if strings.Contains(colName, "mime") {
-			validation["pattern"] = `^[a-z]+\/[a-z0-9\-\+\.]+$`
-			// =====================
-			// TWITTER/X POST ID
-			// =====================
-		} else if strings.Contains(colName, "tweet") && strings.Contains(colName, "id") {
-			validation["pattern"] = `^\d{15,20}$`
-			// =====================
-			// YOUTUBE VIDEO ID
-			// =====================
-		} else if strings.Contains(colName, "youtube") && strings.Contains(colName, "id") {
-			validation["pattern"] = `^[A-Za-z0-9_-]{11}$`
-			// =====================
-			// TEXTO GENERAL
-			// =====================
-		} else {
-			validation["pattern"] = `^.{1,255}$`
+		// UUID (por tipo); el resto de los formatos sale del nombre de la
+		// columna, en los paquetes de reglas
+		if strings.EqualFold(col.DataType, "uuid") || strings.EqualFold(col.DataType, "uniqueidentifier") {
+			validation["pattern"] = `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
 		}
 	case "int", "int32":
 		validation["min"] = -2147483648
@@ -962,14 +802,109 @@ func getValidation(col Column, isRequired bool) map[string]interface{} {
 		validation["max"] = 2100
 	}
 
-	// Validaciones especiales por nombre de campo
-	if strings.Contains(colName, "password") {
-		validation["min_length"] = 8
+	// Rango que admite la precisión declarada de un decimal
+	if strings.HasPrefix(fieldType, "decimal") && col.Precision != nil {
+		scale := 0
+		if col.Scale != nil {
+			scale = *col.Scale
+		}
+		bound := decimalBound(*col.Precision, scale)
+		validation["min"] = "-" + bound
+		validation["max"] = bound
 	}
 
+	// Validaciones por nombre de campo: la primera regla que coincide
+	if _, byType := validation["pattern"]; !byType {
+		category := typeCategory(col)
+		for _, rule := range rules {
+			if rule.matches(colName, category) {
+				for key, value := range rule.Validation {
+					validation[key] = value
+				}
+				break
+			}
+		}
+	}
+
+	// Validaciones por defecto del tipo, configuradas en el proyecto
+	for key, value := range mapping.Validation {
+		validation[key] = value
+	}
+
+	// Reglas de la base de datos (CHECK, enum): mandan sobre las deducidas
+	if len(col.Enum) > 0 {
+		validation["enum"] = enumList(col.Enum, fieldType)
+		delete(validation, "pattern")
+		delete(validation, "min_length")
+		delete(validation, "max_length")
+	}
+	if col.Min != nil {
+		validation["min"] = boundValue(*col.Min)
+	}
+	if col.Max != nil {
+		validation["max"] = boundValue(*col.Max)
+	}
+	if col.Pattern != "" {
+		validation["pattern"] = col.Pattern
+	}
+
+	return validation
+}
+
+// overrideValidation applies the validations set by hand on a field over the
+// deduced ones, database rules included. The message is quoted so it prints
+// as a YAML string.
+func overrideValidation(validation map[string]interface{}, fm FieldMeta, fieldType string) map[string]interface{} {
+	if len(fm.ValEnum) > 0 {
+		validation["enum"] = enumList(fm.ValEnum, fieldType)
+		delete(validation, "pattern")
+		delete(validation, "min_length")
+		delete(validation, "max_length")
+	}
+	if fm.ValMin != nil {
+		validation["min"] = boundValue(*fm.ValMin)
+	}
+	if fm.ValMax != nil {
+		validation["max"] = boundValue(*fm.ValMax)
+	}
+	if fm.ValPattern != "" {
+		validation["pattern"] = fm.ValPattern
+	}
+	if fm.ValMessage != "" {
+		validation["message"] = strconv.Quote(fm.ValMessage)
+	}
 	return validation
 }
 
+// enumList formats enum values as a YAML flow sequence: quoted strings, or
+// bare numbers for numeric fields.
+func enumList(values []string, fieldType string) string {
+	numeric := fieldType != "string"
+	for _, v := range values {
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			numeric = false
+		}
+	}
+	items := make([]string, len(values))
+	for i, v := range values {
+		if numeric {
+			items[i] = v
+		} else {
+			items[i] = strconv.Quote(v)
+		}
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// boundValue returns whole bounds as integers so they print without
+// exponent or decimals.
+func boundValue(v float64) interface{} {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return int64(v)
+	}
+	return v
+}
+
 func getDefault(col Column, fieldType string) interface{} {
 	if col.DefaultValue != nil {
 		defaultStr := *col.DefaultValue
@@ -984,6 +919,14 @@ func getDefault(col Column, fieldType string) interface{} {
 		// Remover comillas simples
 		defaultStr = strings.Trim(defaultStr, "'")
 
+		// Los arrays no tienen un valor simple; los decimales son números
+		if col.ElementType != "" {
+			return nil
+		}
+		if strings.HasPrefix(fieldType, "decimal") {
+			fieldType = "float"
+		}
+
 		switch fieldType {
 		case "bool":
 			if strings.Contains(strings.ToLower(defaultStr), "true") || defaultStr == "1" {
@@ -1011,10 +954,17 @@ func getDefault(col Column, fieldType string) interface{} {
 }
 
 func getDefaultValue(col Column) interface{} {
-	fieldType := formatType(col.DataType)
+	fieldType := templateType(col)
 	return getDefault(col, fieldType)
 }
 
+// yamlEscape prepares a value for a single-quoted YAML string, where a quote
+// is written twice: patterns from CHECK constraints and rule packs can hold
+// quotes.
+func yamlEscape(value interface{}) string {
+	return strings.ReplaceAll(fmt.Sprintf("%v", value), "'", "''")
+}
+
 func toString(value interface{}) string {
 	switch v := value.(type) {
 	case string:
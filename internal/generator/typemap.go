@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TypeMap is a project rule that maps database types to the type of the
+// generated parameters. Pattern is a regular expression matched, ignoring
+// case, against the whole of the domain of the column, its declared type
+// ("numeric(12,2)", "integer[]") or its data type ("numeric"). In SpecType,
+// {precision}, {scale}, {length} and {element} (the mapped type of the
+// elements of an array) are replaced.
+type TypeMap struct {
+	Pattern    string
+	SpecType   string
+	Format     string
+	Validation map[string]interface{}
+
+	re *regexp.Regexp
+}
+
+// NewTypeMap compiles a rule. validation is a JSON object of default
+// validations for the type, or empty.
+func NewTypeMap(pattern, specType, format, validation string) (TypeMap, error) {
+	m := TypeMap{Pattern: pattern, SpecType: strings.TrimSpace(specType), Format: strings.TrimSpace(format)}
+	if strings.TrimSpace(pattern) == "" || m.SpecType == "" {
+		return m, fmt.Errorf("a type mapping needs a database type pattern and a spec type")
+	}
+	re, err := regexp.Compile(`(?i)^(?:` + strings.TrimSpace(pattern) + `)$`)
+	if err != nil {
+		return m, fmt.Errorf("invalid type pattern %q: %v", pattern, err)
+	}
+	m.re = re
+	if strings.TrimSpace(validation) != "" {
+		// Numbers are kept as written so they print without exponent
+		dec := json.NewDecoder(bytes.NewReader([]byte(validation)))
+		dec.UseNumber()
+		if err := dec.Decode(&m.Validation); err != nil {
+			return m, fmt.Errorf("invalid validation of type mapping %q: %v", pattern, err)
+		}
+	}
+	return m, nil
+}
+
+// typeMapping is what the rules, or the built-in mapping, give a column.
+type typeMapping struct {
+	Type       string
+	Format     string
+	Validation map[string]interface{}
+}
+
+// typeMapper holds the rules of a project in order; the first that matches
+// wins and columns no rule matches get templateType.
+type typeMapper []TypeMap
+
+func (m typeMapper) resolve(col Column) typeMapping {
+	for _, rule := range m {
+		// A rule that matches the elements of an array ("timestamp.*" for
+		// "timestamp[]") maps the elements, not the array
+		if rule.matches(col) && (col.ElementType == "" || !rule.matches(Column{DataType: col.ElementType})) {
+			return typeMapping{Type: m.expand(rule.SpecType, col), Format: rule.Format, Validation: rule.Validation}
+		}
+	}
+	if col.ElementType != "" {
+		return typeMapping{Type: "array<" + m.resolve(Column{DataType: col.ElementType}).Type + ">"}
+	}
+	return typeMapping{Type: templateType(col)}
+}
+
+func (t TypeMap) matches(col Column) bool {
+	for _, c := range []string{col.Domain, declaredType(col), col.DataType} {
+		if c != "" && t.re.MatchString(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m typeMapper) expand(specType string, col Column) string {
+	if !strings.Contains(specType, "{") {
+		return specType
+	}
+	element := ""
+	if col.ElementType != "" {
+		element = m.resolve(Column{DataType: col.ElementType}).Type
+	}
+	return strings.NewReplacer(
+		"{precision}", optionalInt(col.Precision),
+		"{scale}", optionalInt(col.Scale),
+		"{length}", optionalInt(col.MaxLength),
+		"{element}", element,
+	).Replace(specType)
+}
+
+// declaredType is the type as declared: "integer[]", "numeric(12,2)",
+// "character varying(50)" or the data type.
+func declaredType(col Column) string {
+	switch {
+	case col.ElementType != "":
+		return col.ElementType + "[]"
+	case col.Precision != nil && col.Scale != nil:
+		return fmt.Sprintf("%s(%d,%d)", col.DataType, *col.Precision, *col.Scale)
+	case col.MaxLength != nil:
+		return fmt.Sprintf("%s(%d)", col.DataType, *col.MaxLength)
+	}
+	return col.DataType
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
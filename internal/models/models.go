@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"time"
 )
 
 type Project struct {
@@ -12,6 +13,7 @@ type Project struct {
 	ModelDir    sql.NullString `json:"modeldir"`
 	ActionDir   sql.NullString `json:"actiondir"`
 	TestDir     sql.NullString `json:"testdir"`
+	RulePacks   sql.NullString `json:"rulepacks"` // "general, latam"; empty uses the default packs
 }
 
 type DbConn struct {
@@ -36,6 +38,7 @@ type Table struct {
 	TableName   string         `json:"tablename"`
 	EntityName  sql.NullString `json:"entityname"`
 	Detail      sql.NullString `json:"detail"`
+	IsView      bool           `json:"isview"`
 }
 
 type TableField struct {
@@ -60,6 +63,23 @@ type TableField struct {
 	ValLength    sql.NullString `json:"val_length"`
 	Auditoria    sql.NullBool   `json:"auditoria"`
 	Detail       sql.NullString `json:"detail"`
+	ChkEnum      sql.NullString `json:"chk_enum"` // JSON array
+	ChkMin       sql.NullString `json:"chk_min"`
+	ChkMax       sql.NullString `json:"chk_max"`
+	ChkPattern   sql.NullString `json:"chk_pattern"`
+	ChkLength    sql.NullString `json:"chk_length"`
+	Ordinal      sql.NullInt32  `json:"ordinal"`
+	IsIdentity   sql.NullString `json:"is_identity"`
+	IsGenerated  sql.NullString `json:"is_generated"`
+	ElementType  sql.NullString `json:"elementtype"`
+	DomainName   sql.NullString `json:"domainname"`
+	NumPrecision sql.NullInt32  `json:"num_precision"`
+	NumScale     sql.NullInt32  `json:"num_scale"`
+	ValMin       sql.NullString `json:"val_min"`
+	ValMax       sql.NullString `json:"val_max"`
+	ValPattern   sql.NullString `json:"val_pattern"`
+	ValEnum      sql.NullString `json:"val_enum"` // JSON array
+	ValMessage   sql.NullString `json:"val_message"`
 }
 
 type FileTemplate struct {
@@ -83,14 +103,62 @@ type Subsystem struct {
 	Details     sql.NullString `json:"details"`
 }
 
+// TypeMap is a type mapping rule of a project: columns whose type matches
+// DbType (a regular expression) get SpecType, Format and the Validation
+// (a JSON object) in the generated parameters.
+type TypeMap struct {
+	ID          int            `json:"id"`
+	ProjectName string         `json:"projectname"`
+	DbType      string         `json:"dbtype"`
+	SpecType    string         `json:"spectype"`
+	Format      sql.NullString `json:"format"`
+	Validation  sql.NullString `json:"validation"`
+	OrderList   int            `json:"orderlist"`
+}
+
 type TableRel struct {
-	Connection string         `json:"connection"`
-	DbName     sql.NullString `json:"dbname"`
-	DbSchema   string         `json:"dbschema"`
-	TableName  string         `json:"tablename"`
-	TypeRel    string         `json:"typerel"`
-	FName      string         `json:"fname"`
-	TableR     string         `json:"tabler"`
-	FNamePk    string         `json:"fnamepk"`
-	IsNull     sql.NullInt32  `json:"is_null"`
+	Connection     string         `json:"connection"`
+	DbName         sql.NullString `json:"dbname"`
+	DbSchema       string         `json:"dbschema"`
+	TableName      string         `json:"tablename"`
+	TypeRel        string         `json:"typerel"`
+	FName          string         `json:"fname"`
+	TableR         string         `json:"tabler"`
+	FNamePk        string         `json:"fnamepk"`
+	IsNull         sql.NullInt32  `json:"is_null"`
+	ConstraintName sql.NullString `json:"constraintname"`
+	Position       int            `json:"position"` // column of a composite key, from 1
+	SchemaR        sql.NullString `json:"schemar"`  // schema of TableR
+}
+
+type TableIndex struct {
+	ProjectName string `json:"projectname"`
+	Connection  string `json:"connection"`
+	DbSchema    string `json:"dbschema"`
+	TableName   string `json:"tablename"`
+	IndexName   string `json:"indexname"`
+	Columns     string `json:"columns"` // comma separated, in index order
+	IsUnique    bool   `json:"isunique"`
+	IsPrimary   bool   `json:"isprimary"`
+}
+
+type Generation struct {
+	ID           int              `json:"id"`
+	ProjectName  string           `json:"projectname"`
+	Connection   string           `json:"connection"`
+	Subsystem    sql.NullString   `json:"subsystem"`
+	Tables       sql.NullString   `json:"tables"`
+	Templates    sql.NullString   `json:"templates"`
+	GeneratedBy  sql.NullString   `json:"generatedby"`
+	GeneratedAt  time.Time        `json:"generatedat"`
+	RolledBackAt sql.NullTime     `json:"rolledbackat"`
+	Files        []GenerationFile `json:"files"`
+}
+
+type GenerationFile struct {
+	GenerationID int            `json:"generation_id"`
+	FilePath     string         `json:"filepath"`
+	BackupPath   sql.NullString `json:"backuppath"`
+	Status       sql.NullString `json:"status"`
+	PrevPristine sql.NullString `json:"-"` // pristine copy the run replaced
 }
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"api-scaffolding/internal/backup"
+	"api-scaffolding/internal/utils"
+)
+
+// handleBackups lists the backups of a project.
+func (s *Server) handleBackups(w http.ResponseWriter, r *http.Request) {
+	projectName := r.URL.Query().Get("projectname")
+	if projectName == "" {
+		http.Error(w, "projectname is required", http.StatusBadRequest)
+		return
+	}
+
+	store, err := s.backupStore(projectName)
+	if err != nil {
+		renderError(w, err, http.StatusBadRequest)
+		return
+	}
+	entries, err := store.List()
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := template.ParseFiles("templates/layout.html", "templates/backups_list.html")
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	policy := store.Policy()
+	data := struct {
+		ProjectName string
+		Dir         string
+		Entries     []backup.Entry
+		KeepCount   int
+		MaxAgeDays  int
+		Purged      string
+	}{
+		ProjectName: projectName,
+		Dir:         store.Dir(),
+		Entries:     entries,
+		KeepCount:   policy.KeepCount,
+		MaxAgeDays:  int(policy.MaxAge / (24 * time.Hour)),
+		Purged:      r.URL.Query().Get("purged"),
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+	}
+}
+
+// handleBackupDiff returns the unified diff between a backup and the current
+// file as JSON.
+func (s *Server) handleBackupDiff(w http.ResponseWriter, r *http.Request) {
+	projectName := r.URL.Query().Get("projectname")
+	path := r.URL.Query().Get("path")
+	if projectName == "" || path == "" {
+		http.Error(w, "projectname and path are required", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := s.BackupDiff(projectName, path)
+	if err != nil {
+		renderError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"path": path,
+		"diff": diff,
+	})
+}
+
+// handleBackupsPurge applies the retention given in the form to the backups
+// of a project.
+func (s *Server) handleBackupsPurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectName := r.FormValue("projectname")
+	if projectName == "" {
+		http.Error(w, "projectname is required", http.StatusBadRequest)
+		return
+	}
+	keep, err := strconv.Atoi(r.FormValue("keep"))
+	if err != nil || keep < 0 {
+		http.Error(w, "invalid keep count", http.StatusBadRequest)
+		return
+	}
+	maxAgeDays, err := strconv.Atoi(r.FormValue("maxagedays"))
+	if err != nil || maxAgeDays < 0 {
+		http.Error(w, "invalid max age", http.StatusBadRequest)
+		return
+	}
+
+	removed, err := s.PurgeBackups(projectName, keep, maxAgeDays)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/backups?projectname=%s&purged=%d", url.QueryEscape(projectName), len(removed)), http.StatusSeeOther)
+}
+
+// backupStore returns the backup store of a project rootdir.
+func (s *Server) backupStore(projectName string) (*backup.Store, error) {
+	rootDir, err := s.getProjectRootDir(projectName)
+	if err != nil {
+		return nil, err
+	}
+	store := backup.NewStore(rootDir, backup.DefaultPolicy)
+	if err := s.keepRollbackBackups(store, projectName); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// keepRollbackBackups protects from retention the backups that generation
+// runs of the project not yet rolled back would restore.
+func (s *Server) keepRollbackBackups(store *backup.Store, projectName string) error {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT f.backuppath
+		FROM %s.generation_files f
+		JOIN %s.generation g ON g.id = f.generation_id
+		WHERE g.projectname = $1 AND g.rolledbackat IS NULL AND f.backuppath IS NOT NULL`, s.cfg.DBSchema, s.cfg.DBSchema), projectName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return err
+		}
+		paths = append(paths, path)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	store.Keep(paths)
+	return nil
+}
+
+// ListBackups returns the backups of a project, newest first per file.
+func (s *Server) ListBackups(projectName string) ([]backup.Entry, error) {
+	store, err := s.backupStore(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return store.List()
+}
+
+// BackupDiff compares a backup of a project with its current file.
+func (s *Server) BackupDiff(projectName, path string) (string, error) {
+	store, err := s.backupStore(projectName)
+	if err != nil {
+		return "", err
+	}
+	entry, err := store.Find(path)
+	if err != nil {
+		return "", err
+	}
+
+	old, err := os.ReadFile(entry.Path)
+	if err != nil {
+		return "", err
+	}
+	current, err := os.ReadFile(entry.File)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return utils.UnifiedDiff(entry.Path, entry.File, string(old), string(current), 3), nil
+}
+
+// PurgeBackups removes the backups of a project beyond keep per file or older
+// than maxAgeDays; zero disables either limit.
+func (s *Server) PurgeBackups(projectName string, keep, maxAgeDays int) ([]backup.Entry, error) {
+	store, err := s.backupStore(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return store.Purge(backup.Policy{
+		KeepCount: keep,
+		MaxAge:    time.Duration(maxAgeDays) * 24 * time.Hour,
+	})
+}
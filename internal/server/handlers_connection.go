@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"strings"
 
+	"api-scaffolding/internal/database"
 	"api-scaffolding/internal/models"
 )
 
@@ -74,9 +76,11 @@ func (s *Server) handleConnectionNew(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		ProjectName string
 		Connection  *models.DbConn
+		Dialects    []database.Dialect
 	}{
 		ProjectName: projectName,
 		Connection:  nil, // New connection
+		Dialects:    database.Dialects(),
 	}
 
 	if err := tmpl.Execute(w, data); err != nil {
@@ -92,16 +96,8 @@ func (s *Server) handleConnectionEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	row := s.db.QueryRow(fmt.Sprintf(`
-		SELECT projectname, connection, dbtype, dbhost, dbport, dbuser, dbpass, dbname, dbschema, dbsslmode, dbtimezone 
-		FROM %s.dbconn 
-		WHERE projectname = $1 AND connection = $2`, s.cfg.DBSchema), projectName, connName)
-
-	var c models.DbConn
-	if err := row.Scan(
-		&c.ProjectName, &c.Connection, &c.DbType, &c.DbHost, &c.DbPort, &c.DbUser, &c.DbPass,
-		&c.DbName, &c.DbSchema, &c.DbSSLMode, &c.DbTimezone,
-	); err != nil {
+	c, err := s.getConnection(projectName, connName)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			http.NotFound(w, r)
 			return
@@ -119,9 +115,11 @@ func (s *Server) handleConnectionEdit(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		ProjectName string
 		Connection  *models.DbConn
+		Dialects    []database.Dialect
 	}{
 		ProjectName: projectName,
 		Connection:  &c,
+		Dialects:    database.Dialects(),
 	}
 
 	if err := tmpl.Execute(w, data); err != nil {
@@ -148,6 +146,15 @@ func (s *Server) handleConnectionSave(w http.ResponseWriter, r *http.Request) {
 	dbTimezone := r.FormValue("dbtimezone")
 	isNew := r.FormValue("is_new") == "true"
 
+	dialect, ok := database.LookupDialect(dbType)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unsupported database type: %s", dbType), http.StatusBadRequest)
+		return
+	}
+	dbType = dialect.Name
+	// Schema list in a canonical form: "sales, security"
+	dbSchema = strings.Join(database.SplitSchemas(dbSchema), ", ")
+
 	var err error
 	if isNew {
 		_, err = s.db.Exec(fmt.Sprintf(`
@@ -169,3 +176,33 @@ func (s *Server) handleConnectionSave(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, fmt.Sprintf("/connections?projectname=%s", projectName), http.StatusSeeOther)
 }
+
+// getConnection loads a single dbconn row.
+func (s *Server) getConnection(projectName, connName string) (models.DbConn, error) {
+	row := s.db.QueryRow(fmt.Sprintf(`
+		SELECT projectname, connection, dbtype, dbhost, dbport, dbuser, dbpass, dbname, dbschema, dbsslmode, dbtimezone 
+		FROM %s.dbconn 
+		WHERE projectname = $1 AND connection = $2`, s.cfg.DBSchema), projectName, connName)
+
+	var c models.DbConn
+	err := row.Scan(
+		&c.ProjectName, &c.Connection, &c.DbType, &c.DbHost, &c.DbPort, &c.DbUser, &c.DbPass,
+		&c.DbName, &c.DbSchema, &c.DbSSLMode, &c.DbTimezone,
+	)
+	return c, err
+}
+
+// dbConfig converts a dbconn row into the scanner configuration.
+func dbConfig(c models.DbConn) *database.DatabaseConfig {
+	return &database.DatabaseConfig{
+		Driver:   c.DbType.String,
+		Host:     c.DbHost.String,
+		Port:     c.DbPort.String,
+		Username: c.DbUser.String,
+		Password: c.DbPass.String,
+		Database: c.DbName.String,
+		SSLMode:  c.DbSSLMode.String,
+		Timezone: c.DbTimezone.String,
+		Schemas:  database.SplitSchemas(c.DbSchema.String),
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+
+	"api-scaffolding/internal/database"
+)
+
+// maxDDLSize bounds the schema scripts accepted by the upload form.
+const maxDDLSize = 10 << 20
+
+// handleImportDDL shows the upload form (GET) and merges an uploaded schema
+// script into the stored metadata of a connection (POST).
+func (s *Server) handleImportDDL(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		projectName := r.URL.Query().Get("projectname")
+		connName := r.URL.Query().Get("connection")
+		if projectName == "" || connName == "" {
+			http.Error(w, "projectname and connection are required", http.StatusBadRequest)
+			return
+		}
+
+		tmpl, err := template.ParseFiles("templates/layout.html", "templates/ddl_import.html")
+		if err != nil {
+			renderError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			ProjectName string
+			Connection  string
+		}{
+			ProjectName: projectName,
+			Connection:  connName,
+		}
+
+		if err := tmpl.Execute(w, data); err != nil {
+			renderError(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxDDLSize); err != nil {
+		http.Error(w, fmt.Sprintf("invalid upload: %v", err), http.StatusBadRequest)
+		return
+	}
+	projectName := r.FormValue("projectname")
+	connName := r.FormValue("connection")
+	if projectName == "" || connName == "" {
+		http.Error(w, "projectname and connection are required", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("ddlfile")
+	if err != nil {
+		http.Error(w, "a schema file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	src, err := io.ReadAll(io.LimitReader(file, maxDDLSize))
+	if err != nil {
+		renderError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	diff, err := s.ScanDDL(projectName, connName, string(src), r.FormValue("flavor"))
+	if err != nil {
+		renderError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	tmpl, err := template.ParseFiles("templates/layout.html", "templates/introspection_summary.html")
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		ProjectName string
+		Connection  string
+		Diff        database.SchemaDiff
+	}{
+		ProjectName: projectName,
+		Connection:  connName,
+		Diff:        diff,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+	}
+}
+
+// ScanDDL merges the tables of a schema script into the stored metadata of a
+// connection, exactly like ScanConnection does with a live scan. Only the
+// tables of the connection schemas are taken; unqualified ones belong to the
+// first. An empty flavor is detected from the script.
+func (s *Server) ScanDDL(projectName, connName, src, flavor string) (database.SchemaDiff, error) {
+	conn, err := s.getConnection(projectName, connName)
+	if err != nil {
+		return database.SchemaDiff{}, err
+	}
+
+	tables, err := database.ParseDDL(src, flavor)
+	if err != nil {
+		return database.SchemaDiff{}, fmt.Errorf("failed to parse DDL: %v", err)
+	}
+
+	schemas := database.SplitSchemas(conn.DbSchema.String)
+	tables = database.TablesInSchemas(tables, schemas)
+	if len(tables) == 0 {
+		return database.SchemaDiff{}, fmt.Errorf("no CREATE TABLE found for schema %s", strings.Join(schemas, ", "))
+	}
+
+	return s.mergeMetadata(conn, tables)
+}
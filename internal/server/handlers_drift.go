@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+
+	"api-scaffolding/internal/database"
+	"api-scaffolding/internal/generator"
+)
+
+// StaleFile is a generated file on disk whose table changed in the database
+// since its metadata was stored.
+type StaleFile struct {
+	Table     string `json:"table"`
+	Subsystem string `json:"subsystem"`
+	File      string `json:"file"`
+}
+
+// DriftReport compares the stored metadata of a connection with its live
+// database.
+type DriftReport struct {
+	Diff       database.SchemaDiff `json:"diff"`
+	StaleFiles []StaleFile         `json:"stale_files"`
+}
+
+// handleDrift shows the drift report of a connection. With format=json the
+// report is returned as JSON.
+func (s *Server) handleDrift(w http.ResponseWriter, r *http.Request) {
+	projectName := r.URL.Query().Get("projectname")
+	connName := r.URL.Query().Get("connection")
+	if projectName == "" || connName == "" {
+		http.Error(w, "projectname and connection are required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.CheckDrift(r.Context(), projectName, connName)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	tmpl, err := template.ParseFiles("templates/layout.html", "templates/drift_report.html")
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		ProjectName string
+		Connection  string
+		Report      DriftReport
+	}{
+		ProjectName: projectName,
+		Connection:  connName,
+		Report:      report,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+	}
+}
+
+// CheckDrift scans the live database of a connection and compares it with the
+// stored metadata without changing it. Files already generated for the
+// changed tables, in any subsystem of the project, are reported as stale.
+func (s *Server) CheckDrift(ctx context.Context, projectName, connName string) (DriftReport, error) {
+	conn, err := s.getConnection(projectName, connName)
+	if err != nil {
+		return DriftReport{}, err
+	}
+
+	stored, metadata, err := s.loadStoredTables(conn)
+	if err != nil {
+		return DriftReport{}, err
+	}
+	live, err := s.scanLive(ctx, conn)
+	if err != nil {
+		return DriftReport{}, err
+	}
+
+	report := DriftReport{Diff: database.DiffTables(stored, live)}
+	if report.Diff.IsEmpty() {
+		return report, nil
+	}
+
+	// Without a rootdir nothing was generated, so nothing can be stale
+	rootDir, err := s.getProjectRootDir(projectName)
+	if err != nil {
+		return report, nil
+	}
+
+	fileTemplates, err := s.ListFileTemplates()
+	if err != nil {
+		return DriftReport{}, err
+	}
+	subsystems, err := s.listSubsystems(projectName)
+	if err != nil {
+		return DriftReport{}, err
+	}
+	subsystemNames := []string{"public"}
+	for _, sub := range subsystems {
+		if !strings.EqualFold(sub.Subsystem, "public") {
+			subsystemNames = append(subsystemNames, sub.Subsystem)
+		}
+	}
+
+	// Tables of the changes, added ones included
+	tableNames := make(map[string]string)
+	for _, tables := range [][]database.Table{stored, live} {
+		for _, t := range tables {
+			tableNames[database.TableKey(t.Schema, t.Name)] = t.Name
+		}
+	}
+
+	for _, key := range report.Diff.Tables() {
+		tableName := tableNames[key]
+		// Generated files are named after the table, whatever its schema,
+		// and the entity as the generator names it
+		entityName := strings.ToLower(generator.EntityName(tableName, metadata[key].EntityName))
+
+		for _, subsystem := range subsystemNames {
+			for _, ft := range fileTemplates {
+				path := outputPath(rootDir, subsystem, ft, tableName, entityName)
+				if _, err := os.Stat(path); err == nil {
+					report.StaleFiles = append(report.StaleFiles, StaleFile{
+						Table:     tableName,
+						Subsystem: subsystem,
+						File:      path,
+					})
+				}
+			}
+		}
+	}
+
+	return report, nil
+}
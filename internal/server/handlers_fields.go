@@ -0,0 +1,218 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"api-scaffolding/internal/database"
+	"api-scaffolding/internal/models"
+)
+
+// handleTableFields renders the editable grid of tablesfields for one table.
+func (s *Server) handleTableFields(w http.ResponseWriter, r *http.Request) {
+	projectName := r.URL.Query().Get("projectname")
+	connName := r.URL.Query().Get("connection")
+	schema := r.URL.Query().Get("dbschema")
+	tableName := r.URL.Query().Get("tablename")
+	if projectName == "" || connName == "" || schema == "" || tableName == "" {
+		http.Error(w, "projectname, connection, dbschema and tablename are required", http.StatusBadRequest)
+		return
+	}
+
+	table, err := s.getTable(projectName, connName, schema, tableName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := s.listTableFields(projectName, connName, schema, tableName)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := template.ParseFiles("templates/layout.html", "templates/table_fields.html")
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		ProjectName string
+		Connection  string
+		Table       models.Table
+		Fields      []models.TableField
+		Saved       bool
+	}{
+		ProjectName: projectName,
+		Connection:  connName,
+		Table:       table,
+		Fields:      fields,
+		Saved:       r.URL.Query().Get("saved") == "1",
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+	}
+}
+
+// handleTableFieldsSave stores the edited grid. Every row is updated inside a
+// single transaction so a failing row leaves the metadata untouched.
+func (s *Server) handleTableFieldsSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectName := r.FormValue("projectname")
+	connName := r.FormValue("connection")
+	schema := r.FormValue("dbschema")
+	tableName := r.FormValue("tablename")
+	if projectName == "" || connName == "" || schema == "" || tableName == "" {
+		http.Error(w, "projectname, connection, dbschema and tablename are required", http.StatusBadRequest)
+		return
+	}
+
+	rowCount, err := strconv.Atoi(r.FormValue("rows"))
+	if err != nil || rowCount < 0 {
+		http.Error(w, "invalid rows count", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(fmt.Sprintf(`
+		UPDATE %s.tables SET entityname = $5
+		WHERE projectname = $1 AND connection = $2 AND dbschema = $3 AND tablename = $4`, s.cfg.DBSchema),
+		projectName, connName, schema, tableName, strings.TrimSpace(r.FormValue("entityname")))
+	if err != nil {
+		renderError(w, fmt.Errorf("failed to update table %s: %v", tableName, err), http.StatusInternalServerError)
+		return
+	}
+
+	for i := 0; i < rowCount; i++ {
+		field := func(name string) string {
+			return strings.TrimSpace(r.FormValue(fmt.Sprintf("%s_%d", name, i)))
+		}
+		checked := func(name string) bool {
+			return r.FormValue(fmt.Sprintf("%s_%d", name, i)) != ""
+		}
+
+		fieldName := field("fieldname")
+		if fieldName == "" {
+			continue
+		}
+
+		orderList, err := strconv.Atoi(field("orderlist"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid order for field %s", fieldName), http.StatusBadRequest)
+			return
+		}
+
+		if v := field("val_length"); v != "" {
+			if length, err := strconv.Atoi(v); err != nil || length <= 0 {
+				http.Error(w, fmt.Sprintf("invalid length for field %s: %q is not a positive number", fieldName, v), http.StatusBadRequest)
+				return
+			}
+		}
+		for _, bound := range []string{"val_min", "val_max"} {
+			if v := field(bound); v != "" && database.ParseBound(v) == nil {
+				http.Error(w, fmt.Sprintf("invalid %s for field %s: %q is not a number", strings.TrimPrefix(bound, "val_"), fieldName, v), http.StatusBadRequest)
+				return
+			}
+		}
+		if _, err := regexp.Compile(field("val_pattern")); err != nil {
+			http.Error(w, fmt.Sprintf("invalid pattern for field %s: %v", fieldName, err), http.StatusBadRequest)
+			return
+		}
+		valEnum, err := enumJSON(field("val_enum"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid enum for field %s: %v", fieldName, err), http.StatusBadRequest)
+			return
+		}
+
+		_, err = tx.Exec(fmt.Sprintf(`
+			UPDATE %s.tablesfields SET
+				label = $6, labelhelp = $7, orderlist = $8, inlist = $9, incrud = $10,
+				val_length = $11, auditoria = $12, detail = $13,
+				val_min = $14, val_max = $15, val_pattern = $16, val_enum = $17, val_message = $18
+			WHERE projectname = $1 AND connection = $2 AND dbschema = $3 AND tablename = $4 AND fieldname = $5`, s.cfg.DBSchema),
+			projectName, connName, schema, tableName, fieldName,
+			field("label"), field("labelhelp"), orderList, boolToSmallint(checked("inlist")), boolToSmallint(checked("incrud")),
+			nullIfEmpty(field("val_length")), checked("auditoria"), field("detail"),
+			nullIfEmpty(field("val_min")), nullIfEmpty(field("val_max")), nullIfEmpty(field("val_pattern")), nullIfEmpty(valEnum), nullIfEmpty(field("val_message")),
+		)
+		if err != nil {
+			renderError(w, fmt.Errorf("failed to update field %s.%s: %v", tableName, fieldName, err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/connections/tables/fields?projectname=%s&connection=%s&dbschema=%s&tablename=%s&saved=1",
+		url.QueryEscape(projectName), url.QueryEscape(connName), url.QueryEscape(schema), url.QueryEscape(tableName)), http.StatusSeeOther)
+}
+
+// getTable loads a single row of the tables metadata.
+func (s *Server) getTable(projectName, connName, schema, tableName string) (models.Table, error) {
+	var t models.Table
+	err := s.db.QueryRow(fmt.Sprintf(`
+		SELECT projectname, connection, dbname, dbschema, tablename, entityname, detail, isview
+		FROM %s.tables
+		WHERE projectname = $1 AND connection = $2 AND dbschema = $3 AND tablename = $4`, s.cfg.DBSchema), projectName, connName, schema, tableName).Scan(
+		&t.ProjectName, &t.Connection, &t.DbName, &t.DbSchema, &t.TableName, &t.EntityName, &t.Detail, &t.IsView,
+	)
+	return t, err
+}
+
+// enumJSON stores the enum values typed in the grid, a JSON array or a comma
+// separated list, as a JSON array like chk_enum. Empty input gives "".
+func enumJSON(input string) (string, error) {
+	if input == "" {
+		return "", nil
+	}
+	var values []string
+	if strings.HasPrefix(input, "[") {
+		if err := json.Unmarshal([]byte(input), &values); err != nil {
+			return "", err
+		}
+	} else {
+		for _, v := range strings.Split(input, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(values)
+	return string(data), err
+}
+
+func boolToSmallint(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -5,30 +5,28 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
-	"time"
 
+	"api-scaffolding/internal/backup"
 	"api-scaffolding/internal/database"
 	"api-scaffolding/internal/generator"
 	"api-scaffolding/internal/models"
+	"api-scaffolding/internal/utils"
 )
 
 // handleFileTemplatesList returns the list of file_templates as JSON (used by the front-end).
 func (s *Server) handleFileTemplatesList(w http.ResponseWriter, r *http.Request) {
-	rows, err := s.db.Query(fmt.Sprintf(`
-		SELECT id, version, grouptype, category, name, path, file, template, source, orderlist, visible, typefile
-		FROM %s.file_templates
-		WHERE visible = 1
-		ORDER BY orderlist`, s.cfg.DBSchema))
+	templates, err := s.ListFileTemplates()
 	if err != nil {
 		renderError(w, err, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
 	// DTO with plain strings — avoids sql.NullString serialising as {"String":"...","Valid":true}
 	type FileTemplateDTO struct {
@@ -42,205 +40,281 @@ func (s *Server) handleFileTemplatesList(w http.ResponseWriter, r *http.Request)
 		Template  string `json:"template"`
 		Source    string `json:"source"`
 		OrderList int32  `json:"orderlist"`
-		Visible   int16  `json:"visible"`
+		Visible   int32  `json:"visible"`
 		TypeFile  string `json:"typefile"`
 	}
 
-	var result []FileTemplateDTO
-	for rows.Next() {
-		var (
-			version, grouptype, category, name sql.NullString
-			path, file, tmpl, source           sql.NullString
-			orderlist                          sql.NullInt32
-			visible                            sql.NullInt16
-			typefile                           sql.NullString
-			id                                 int
-		)
-		if err := rows.Scan(
-			&id, &version, &grouptype, &category, &name,
-			&path, &file, &tmpl, &source,
-			&orderlist, &visible, &typefile,
-		); err != nil {
-			renderError(w, err, http.StatusInternalServerError)
-			return
-		}
+	result := []FileTemplateDTO{} // return [] not null
+	for _, ft := range templates {
 		result = append(result, FileTemplateDTO{
-			ID:        id,
-			Version:   version.String,
-			GroupType: grouptype.String,
-			Category:  category.String,
-			Name:      name.String,
-			Path:      path.String,
-			File:      file.String,
-			Template:  tmpl.String,
-			Source:    source.String,
-			OrderList: orderlist.Int32,
-			Visible:   visible.Int16,
-			TypeFile:  typefile.String,
+			ID:        ft.ID,
+			Version:   ft.Version.String,
+			GroupType: ft.GroupType.String,
+			Category:  ft.Category.String,
+			Name:      ft.Name.String,
+			Path:      ft.Path.String,
+			File:      ft.File.String,
+			Template:  ft.Template.String,
+			Source:    ft.Source.String,
+			OrderList: ft.OrderList.Int32,
+			Visible:   ft.Visible.Int32,
+			TypeFile:  ft.TypeFile.String,
 		})
 	}
 
-	if result == nil {
-		result = []FileTemplateDTO{} // return [] not null
-	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
-// handleGenerate processes the "Generate" button: receives selected tables + selected
-// file_templates, resolves paths, applies Go templates and writes yaml files.
-func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	projectName := r.FormValue("projectname")
-	connName := r.FormValue("connection")
-	subsystem := r.FormValue("subsystem")
-	selectedTables := r.Form["tables[]"]
-	selectedTemplateIDs := r.Form["file_templates[]"]
-
-	if projectName == "" || connName == "" {
-		http.Error(w, "projectname and connection are required", http.StatusBadRequest)
-		return
-	}
-	if subsystem == "" {
-		subsystem = "public"
-	}
-	if len(selectedTables) == 0 || len(selectedTemplateIDs) == 0 {
-		http.Error(w, "select at least one table and one template", http.StatusBadRequest)
-		return
-	}
+// ListFileTemplates returns the visible file_templates ordered by orderlist.
+func (s *Server) ListFileTemplates() ([]models.FileTemplate, error) {
+	return s.queryFileTemplates(fmt.Sprintf(`
+		SELECT id, version, grouptype, category, name, path, file, template, source, orderlist, visible, typefile
+		FROM %s.file_templates
+		WHERE visible = 1
+		ORDER BY orderlist`, s.cfg.DBSchema))
+}
 
-	// --- 1. Get project rootdir ---
-	var rootDir sql.NullString
-	if err := s.db.QueryRow(fmt.Sprintf(`SELECT rootdir FROM %s.project WHERE projectname=$1`, s.cfg.DBSchema), projectName).Scan(&rootDir); err != nil {
-		renderError(w, fmt.Errorf("project not found: %v", err), http.StatusInternalServerError)
-		return
+// getFileTemplates returns the file_templates with the given ids. A single "*"
+// selects every visible template.
+func (s *Server) getFileTemplates(ids []string) ([]models.FileTemplate, error) {
+	if len(ids) == 1 && ids[0] == "*" {
+		return s.ListFileTemplates()
 	}
 
-	// --- 2. Get selected file_templates ---
-	placeholders := make([]string, len(selectedTemplateIDs))
-	args := make([]interface{}, len(selectedTemplateIDs))
-	for i, id := range selectedTemplateIDs {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
 		placeholders[i] = fmt.Sprintf("$%d", i+1)
 		args[i] = id
 	}
-	query := fmt.Sprintf(`
+	return s.queryFileTemplates(fmt.Sprintf(`
 		SELECT id, version, grouptype, category, name, path, file, template, source, orderlist, visible, typefile
 		FROM %s.file_templates
 		WHERE id IN (%s)
-		ORDER BY orderlist`, s.cfg.DBSchema, strings.Join(placeholders, ","))
+		ORDER BY orderlist`, s.cfg.DBSchema, strings.Join(placeholders, ",")), args...)
+}
 
-	tplRows, err := s.db.Query(query, args...)
+func (s *Server) queryFileTemplates(query string, args ...interface{}) ([]models.FileTemplate, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		renderError(w, err, http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	defer tplRows.Close()
+	defer rows.Close()
 
 	var fileTemplates []models.FileTemplate
-	for tplRows.Next() {
+	for rows.Next() {
 		var ft models.FileTemplate
-		if err := tplRows.Scan(
+		if err := rows.Scan(
 			&ft.ID, &ft.Version, &ft.GroupType, &ft.Category, &ft.Name,
 			&ft.Path, &ft.File, &ft.Template, &ft.Source,
 			&ft.OrderList, &ft.Visible, &ft.TypeFile,
 		); err != nil {
-			renderError(w, err, http.StatusInternalServerError)
-			return
+			return nil, err
 		}
 		fileTemplates = append(fileTemplates, ft)
 	}
+	return fileTemplates, rows.Err()
+}
+
+// GenerateRequest describes a generation run: which tables of a connection are
+// rendered with which file_templates, and for which subsystem.
+type GenerateRequest struct {
+	ProjectName string
+	Connection  string
+	Subsystem   string
+	Tables      []string // a single "*" selects every table of the connection
+	TemplateIDs []string // a single "*" selects every visible file_template
+	DryRun      bool     // render in memory and compare with the files on disk
+	User        string   // who requested the run, recorded in the history
+}
+
+// GenerateResult is the outcome of generating a single file. On a dry run the
+// status is "new", "unchanged" or "modified" and Diff holds the unified diff
+// against the current file. Write templates are "skipped" for views.
+type GenerateResult struct {
+	File    string `json:"file"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Diff    string `json:"diff,omitempty"`
+	Backup  string `json:"backup,omitempty"` // previous version of the file, if any
+
+	prevPristine sql.NullString // pristine copy replaced by this run, if any
+}
+
+// handleGenerate processes the "Generate" button: receives selected tables + selected
+// file_templates, resolves paths, applies Go templates and writes yaml files.
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := GenerateRequest{
+		ProjectName: r.FormValue("projectname"),
+		Connection:  r.FormValue("connection"),
+		Subsystem:   r.FormValue("subsystem"),
+		Tables:      r.Form["tables[]"],
+		TemplateIDs: r.Form["file_templates[]"],
+		DryRun:      r.FormValue("dryrun") == "1",
+		User:        requestUser(r),
+	}
 
-	// --- 3. Get connection details to connect to target DB ---
-	var dbType, dbHost, dbPort, dbUser, dbPass, dbNameNS, dbSchemaNS, dbSslMode, dbTimezone sql.NullString
-	row := s.db.QueryRow(fmt.Sprintf(`
-		SELECT dbtype, dbhost, dbport, dbuser, dbpass, dbname, dbschema, dbsslmode, dbtimezone
-		FROM %s.dbconn
-		WHERE projectname=$1 AND connection=$2`, s.cfg.DBSchema), projectName, connName)
-	if err := row.Scan(&dbType, &dbHost, &dbPort, &dbUser, &dbPass, &dbNameNS, &dbSchemaNS, &dbSslMode, &dbTimezone); err != nil {
-		renderError(w, fmt.Errorf("connection not found: %v", err), http.StatusInternalServerError)
+	if req.ProjectName == "" || req.Connection == "" {
+		http.Error(w, "projectname and connection are required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Tables) == 0 || len(req.TemplateIDs) == 0 {
+		http.Error(w, "select at least one table and one template", http.StatusBadRequest)
 		return
 	}
 
-	// --- 4. Connect to target database and get table metadata ---
-	cfg := &database.DatabaseConfig{
-		Driver:   dbType.String,
-		Host:     dbHost.String,
-		Port:     dbPort.String,
-		Username: dbUser.String,
-		Password: dbPass.String,
-		Database: dbNameNS.String,
-		SSLMode:  dbSslMode.String,
-		Timezone: dbTimezone.String,
-	}
-	scanner := database.NewScanner()
-	if err := scanner.Connect(cfg); err != nil {
-		renderError(w, fmt.Errorf("cannot connect to target db: %v", err), http.StatusInternalServerError)
+	results, err := s.Generate(req)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
 		return
 	}
-	defer scanner.Disconnect()
 
-	targetSchema := dbSchemaNS.String
-	if targetSchema == "" {
-		targetSchema = "public"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+	})
+}
+
+// Generate renders every selected table × file_template and writes the files
+// under the project rootdir, or only previews them when req.DryRun is set.
+// Failures that abort the whole run are returned as
+// an error; failures of a single file are reported in its GenerateResult.
+func (s *Server) Generate(req GenerateRequest) ([]GenerateResult, error) {
+	if len(req.Tables) == 0 || len(req.TemplateIDs) == 0 {
+		return nil, fmt.Errorf("select at least one table and one template")
+	}
+
+	subsystem := req.Subsystem
+	if subsystem == "" {
+		subsystem = "public"
+	}
+
+	// --- 1. Get project rootdir and rule packs ---
+	var rootDir, rulePacks sql.NullString
+	if err := s.db.QueryRow(fmt.Sprintf(`SELECT rootdir, rulepacks FROM %s.project WHERE projectname=$1`, s.cfg.DBSchema), req.ProjectName).Scan(&rootDir, &rulePacks); err != nil {
+		return nil, fmt.Errorf("project not found: %v", err)
+	}
+
+	// --- 2. Get selected file_templates ---
+	fileTemplates, err := s.getFileTemplates(req.TemplateIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileTemplates) == 0 {
+		return nil, fmt.Errorf("no file templates found for %v", req.TemplateIDs)
 	}
 
-	allTables, err := scanner.GetTables(targetSchema, []string{"*"})
+	// --- 3. Get connection details ---
+	conn, err := s.getConnection(req.ProjectName, req.Connection)
 	if err != nil {
-		renderError(w, fmt.Errorf("cannot get tables: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("connection not found: %v", err)
 	}
 
-	// Build a lookup by table name
+	// --- 4. Load the curated table metadata stored for the connection ---
+	allTables, metadata, err := s.loadStoredTables(conn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load table metadata: %v", err)
+	}
+	if len(allTables) == 0 {
+		return nil, fmt.Errorf("no table metadata stored for connection %s; run \"Get info tables\" first", req.Connection)
+	}
+
+	// Build a lookup by "schema.table" and by table name; a bare name
+	// shared by several schemas is ambiguous and has to be qualified
 	tableMap := make(map[string]database.Table)
+	schemasOf := make(map[string][]string)
 	for _, t := range allTables {
+		tableMap[database.TableKey(t.Schema, t.Name)] = t
 		tableMap[strings.ToLower(t.Name)] = t
+		schemasOf[strings.ToLower(t.Name)] = append(schemasOf[strings.ToLower(t.Name)], t.Schema)
+	}
+
+	selectedTables := req.Tables
+	if len(selectedTables) == 1 && selectedTables[0] == "*" {
+		selectedTables = make([]string, 0, len(allTables))
+		for _, t := range allTables {
+			selectedTables = append(selectedTables, t.Schema+"."+t.Name)
+		}
 	}
 
 	// --- 5. Build the TemplateProcessor loading from templatesgen/ ---
 	tp, err := generator.NewTemplateProcessor("templatesgen")
 	if err != nil {
-		renderError(w, fmt.Errorf("cannot load templates: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("cannot load templates: %v", err)
 	}
 
 	// Generator config (needed for prepareTemplateData)
 	genConfig := &generator.Config{
-		DBDriver:         dbType.String,
-		DBHost:           dbHost.String,
-		DBPort:           dbPort.String,
-		DBUsername:       dbUser.String,
-		DBPassword:       dbPass.String,
-		DBName:           dbNameNS.String,
-		DBSSLMode:        dbSslMode.String,
+		DBDriver:         conn.DbType.String,
+		DBHost:           conn.DbHost.String,
+		DBPort:           conn.DbPort.String,
+		DBUsername:       conn.DbUser.String,
+		DBPassword:       conn.DbPass.String,
+		DBName:           conn.DbName.String,
+		DBSSLMode:        conn.DbSSLMode.String,
 		ProjectDir:       rootDir.String,
-		ProjectSchema:    targetSchema,
+		ProjectSchema:    strings.Join(database.SplitSchemas(conn.DbSchema.String), ","),
 		ProjectFileTypes: "yaml",
 		ProjectRelations: []string{"*"},
 	}
-	gen := generator.NewGenerator(genConfig, scanner, tp)
-
-	type GenerateResult struct {
-		File    string `json:"file"`
-		Status  string `json:"status"`
-		Message string `json:"message,omitempty"`
+	gen := generator.NewGenerator(genConfig, nil, tp)
+	gen.SetMetadata(metadata)
+	typeMaps, err := s.loadTypeMaps(req.ProjectName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load type mappings: %v", err)
+	}
+	gen.SetTypeMaps(typeMaps)
+	validationRules, err := generator.LoadValidationRules(rulePacksDir, generator.SelectedRulePacks(rulePacks.String))
+	if err != nil {
+		return nil, fmt.Errorf("cannot load validation rule packs: %v", err)
 	}
+	gen.SetValidationRules(validationRules)
+	manifest := generator.NewManifest(rootDir.String)
+	backups := backup.NewStore(rootDir.String, backup.DefaultPolicy)
+	if err := s.keepRollbackBackups(backups, req.ProjectName); err != nil {
+		return nil, fmt.Errorf("cannot load generation history: %v", err)
+	}
+
 	var results []GenerateResult
 
+	// Files are named after the table, not its schema: same-named tables of
+	// two schemas can't be generated into the same subsystem
+	generatedAs := make(map[string]database.Table)
+
 	// --- 6. For each selected table × each selected file_template → generate ---
 	for _, tableName := range selectedTables {
+		if schemas := schemasOf[strings.ToLower(tableName)]; len(schemas) > 1 {
+			results = append(results, GenerateResult{
+				File:    tableName,
+				Status:  "error",
+				Message: fmt.Sprintf("table %s exists in schemas %s; select it as schema.table", tableName, strings.Join(schemas, ", ")),
+			})
+			continue
+		}
 		table, ok := tableMap[strings.ToLower(tableName)]
 		if !ok {
 			results = append(results, GenerateResult{
 				File:    tableName,
 				Status:  "error",
-				Message: "table not found in database",
+				Message: "table not found in stored metadata",
 			})
 			continue
 		}
+		if other, clash := generatedAs[strings.ToLower(table.Name)]; clash && other.Schema != table.Schema {
+			results = append(results, GenerateResult{
+				File:    tableName,
+				Status:  "error",
+				Message: fmt.Sprintf("%s.%s writes the same files as %s.%s; generate it into another subsystem", table.Schema, table.Name, other.Schema, other.Name),
+			})
+			continue
+		}
+		generatedAs[strings.ToLower(table.Name)] = table
 
 		templateData := gen.PrepareTemplateDataPublic(table, allTables)
 
@@ -257,20 +331,19 @@ func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
 			// TemplateProcessor keys are the basename
 			templateBasename := filepath.Base(templateFile)
 
-			// Resolve output path: replace [rootprj], [subsystem] and [entity]
-			// New directory structure: [rootprj]/[subsystem]/[entity]/
-			outPath := strings.ReplaceAll(ft.Path.String, "[rootprj]", rootDir.String)
-			outPath = strings.ReplaceAll(outPath, "[subsystem]", strings.ToLower(subsystem))
-			outPath = strings.ReplaceAll(outPath, "[entity]", strings.ToLower(tableName))
-			// If the path pattern doesn't contain [subsystem], insert it after rootdir
-			if !strings.Contains(ft.Path.String, "[subsystem]") {
-				outPath = strings.ReplaceAll(ft.Path.String, "[rootprj]", filepath.Join(rootDir.String, strings.ToLower(subsystem)))
-				outPath = strings.ReplaceAll(outPath, "[entity]", strings.ToLower(tableName))
+			fullPath := outputPath(rootDir.String, subsystem, ft, table.Name, entityName)
+
+			// Views only get the templates that don't write
+			if table.IsView && !generator.IsReadOnlyTemplate(templateBasename) {
+				results = append(results, GenerateResult{
+					File:    fullPath,
+					Status:  "skipped",
+					Message: fmt.Sprintf("%s is a view: only list, get and report templates apply", table.Name),
+				})
+				continue
 			}
-			outFile := strings.ReplaceAll(ft.File.String, "[entity]", entityName)
-			fullPath := filepath.Join(outPath, outFile)
 
-			content, err := tp.Process(templateBasename, templateData)
+			rendered, err := tp.Process(templateBasename, templateData)
 			if err != nil {
 				results = append(results, GenerateResult{
 					File:    fullPath,
@@ -280,7 +353,46 @@ func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 
-			if err := writeFileSafe(fullPath, content); err != nil {
+			// Keep the hand edits of the current file
+			content, conflicts, note, err := mergeWithCurrent(manifest, fullPath, rendered)
+			if err != nil {
+				results = append(results, GenerateResult{
+					File:    fullPath,
+					Status:  "error",
+					Message: err.Error(),
+				})
+				continue
+			}
+
+			status := "ok"
+			if conflicts > 0 {
+				status = "conflict"
+			}
+
+			if req.DryRun {
+				result := previewFile(fullPath, content)
+				if conflicts > 0 {
+					result.Status = status
+				}
+				if result.Message == "" {
+					result.Message = note
+				}
+				results = append(results, result)
+				continue
+			}
+
+			prevPristine, hadPristine, err := manifest.Pristine(fullPath)
+			if err != nil {
+				results = append(results, GenerateResult{
+					File:    fullPath,
+					Status:  "error",
+					Message: fmt.Sprintf("manifest error: %v", err),
+				})
+				continue
+			}
+
+			backupPath, err := writeFileSafe(backups, fullPath, content)
+			if err != nil {
 				results = append(results, GenerateResult{
 					File:    fullPath,
 					Status:  "error",
@@ -289,29 +401,140 @@ func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 
+			if err := manifest.Record(fullPath, rendered); err != nil {
+				results = append(results, GenerateResult{
+					File:    fullPath,
+					Status:  "error",
+					Message: fmt.Sprintf("manifest error: %v", err),
+				})
+				continue
+			}
+
 			results = append(results, GenerateResult{
-				File:   fullPath,
-				Status: "ok",
+				File:         fullPath,
+				Status:       status,
+				Message:      note,
+				Backup:       backupPath,
+				prevPristine: sql.NullString{String: prevPristine, Valid: hadPristine},
 			})
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"results": results,
-	})
+	if !req.DryRun {
+		templateIDs := make([]string, len(fileTemplates))
+		for i, ft := range fileTemplates {
+			templateIDs[i] = strconv.Itoa(ft.ID)
+		}
+		if err := s.recordGeneration(req, subsystem, selectedTables, templateIDs, results); err != nil {
+			log.Printf("Error recording generation history: %v", err)
+		}
+	}
+
+	return results, nil
+}
+
+// outputPath resolves the file written for a table by a file_template,
+// replacing [rootprj], [subsystem] and [entity] in its path and file patterns.
+// New directory structure: [rootprj]/[subsystem]/[entity]/
+func outputPath(rootDir, subsystem string, ft models.FileTemplate, tableName, entityName string) string {
+	outPath := strings.ReplaceAll(ft.Path.String, "[rootprj]", rootDir)
+	outPath = strings.ReplaceAll(outPath, "[subsystem]", strings.ToLower(subsystem))
+	outPath = strings.ReplaceAll(outPath, "[entity]", strings.ToLower(tableName))
+	// If the path pattern doesn't contain [subsystem], insert it after rootdir
+	if !strings.Contains(ft.Path.String, "[subsystem]") {
+		outPath = strings.ReplaceAll(ft.Path.String, "[rootprj]", filepath.Join(rootDir, strings.ToLower(subsystem)))
+		outPath = strings.ReplaceAll(outPath, "[entity]", strings.ToLower(tableName))
+	}
+	outFile := strings.ReplaceAll(ft.File.String, "[entity]", strings.ToLower(entityName))
+	return filepath.Join(outPath, outFile)
+}
+
+// mergeWithCurrent reconciles a new rendering with the file on disk. The
+// protected regions are carried over first; then, when the manifest holds the
+// pristine output of the previous run, edits made anywhere else in the file
+// are three-way merged and clashes are marked as conflicts. Files generated
+// before the manifest existed are replaced as before.
+func mergeWithCurrent(manifest *generator.Manifest, path, rendered string) (string, int, string, error) {
+	content, note, err := carryOverRegions(path, rendered)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("protected regions: %v", err)
+	}
+
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return content, 0, note, nil
+	}
+	if err != nil {
+		return "", 0, "", fmt.Errorf("read error: %v", err)
+	}
+	pristine, ok, err := manifest.Pristine(path)
+	if err != nil {
+		return "", 0, "", err
+	}
+	if !ok {
+		return content, 0, note, nil
+	}
+
+	merged, conflicts := utils.Merge3(pristine, string(current), content)
+	if conflicts > 0 {
+		msg := fmt.Sprintf("%d conflicts between hand edits and the new output, marked in the file", conflicts)
+		if note != "" {
+			msg += "; " + note
+		}
+		note = msg
+	}
+	return merged, conflicts, note, nil
+}
+
+// carryOverRegions copies the protected regions of the file currently at path
+// into the generated content. The returned note names the regions the
+// template no longer declares; their content survives only in the backup.
+func carryOverRegions(path, content string) (string, string, error) {
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return content, "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	merged, dropped, err := generator.MergeRegions(string(current), content)
+	if err != nil {
+		return "", "", err
+	}
+	note := ""
+	if len(dropped) > 0 {
+		note = fmt.Sprintf("regions no longer in the template, kept only in the backup: %s", strings.Join(dropped, ", "))
+	}
+	return merged, note, nil
+}
+
+// previewFile compares generated content with the file currently on disk.
+func previewFile(path, content string) GenerateResult {
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return GenerateResult{File: path, Status: "new", Diff: utils.UnifiedDiff("/dev/null", path, "", content, 3)}
+	}
+	if err != nil {
+		return GenerateResult{File: path, Status: "error", Message: fmt.Sprintf("read error: %v", err)}
+	}
+	if string(current) == content {
+		return GenerateResult{File: path, Status: "unchanged"}
+	}
+	return GenerateResult{File: path, Status: "modified", Diff: utils.UnifiedDiff(path, path, string(current), content, 3)}
 }
 
 // writeFileSafe creates directories, backs-up existing file and writes content.
-func writeFileSafe(path, content string) error {
+// It returns the path of the backup, empty when the file is new.
+func writeFileSafe(backups *backup.Store, path, content string) (string, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
+		return "", err
 	}
-	if _, err := os.Stat(path); err == nil {
-		backupPath := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102150405"))
-		_ = os.Rename(path, backupPath)
+	backupPath, err := backups.Backup(path)
+	if err != nil {
+		return "", fmt.Errorf("backup failed: %v", err)
 	}
-	return os.WriteFile(path, []byte(content), 0644)
+	return backupPath, os.WriteFile(path, []byte(content), 0644)
 }
 
 // processTemplate executes a Go text/template string with the given data.
@@ -0,0 +1,304 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"api-scaffolding/internal/generator"
+	"api-scaffolding/internal/models"
+)
+
+// handleHistory lists the generation runs of a connection, newest first.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	projectName := r.URL.Query().Get("projectname")
+	connName := r.URL.Query().Get("connection")
+	if projectName == "" || connName == "" {
+		http.Error(w, "projectname and connection are required", http.StatusBadRequest)
+		return
+	}
+
+	generations, err := s.ListGenerations(projectName, connName)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := template.ParseFiles("templates/layout.html", "templates/generation_history.html")
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		ProjectName string
+		Connection  string
+		Generations []models.Generation
+		RolledBack  string
+	}{
+		ProjectName: projectName,
+		Connection:  connName,
+		Generations: generations,
+		RolledBack:  r.URL.Query().Get("rolledback"),
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+	}
+}
+
+// handleHistoryRollback undoes a generation run and goes back to the history.
+func (s *Server) handleHistoryRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "invalid generation id", http.StatusBadRequest)
+		return
+	}
+
+	gen, err := s.Rollback(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		renderError(w, err, http.StatusConflict)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/connections/history?projectname=%s&connection=%s&rolledback=%d",
+		url.QueryEscape(gen.ProjectName), url.QueryEscape(gen.Connection), gen.ID), http.StatusSeeOther)
+}
+
+// recordGeneration stores a generation run and the files it wrote.
+func (s *Server) recordGeneration(req GenerateRequest, subsystem string, tables, templateIDs []string, results []GenerateResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(fmt.Sprintf(`
+		INSERT INTO %s.generation (projectname, connection, subsystem, tables, templates, generatedby)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`, s.cfg.DBSchema),
+		req.ProjectName, req.Connection, subsystem, strings.Join(tables, ","), strings.Join(templateIDs, ","), req.User,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		// Only files that were actually written can be rolled back
+		if res.Status != "ok" && res.Status != "conflict" {
+			continue
+		}
+		var backup sql.NullString
+		if res.Backup != "" {
+			backup = sql.NullString{String: res.Backup, Valid: true}
+		}
+		_, err := tx.Exec(fmt.Sprintf(`
+			INSERT INTO %s.generation_files (generation_id, filepath, backuppath, status, prevpristine)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (generation_id, filepath) DO NOTHING`, s.cfg.DBSchema),
+			id, res.File, backup, res.Status, res.prevPristine)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListGenerations returns the generation runs of a connection with their
+// files, newest first.
+func (s *Server) ListGenerations(projectName, connName string) ([]models.Generation, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT id, projectname, connection, subsystem, tables, templates, generatedby, generatedat, rolledbackat
+		FROM %s.generation
+		WHERE projectname = $1 AND connection = $2
+		ORDER BY id DESC`, s.cfg.DBSchema), projectName, connName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var generations []models.Generation
+	index := make(map[int]int)
+	for rows.Next() {
+		var g models.Generation
+		if err := rows.Scan(&g.ID, &g.ProjectName, &g.Connection, &g.Subsystem, &g.Tables, &g.Templates,
+			&g.GeneratedBy, &g.GeneratedAt, &g.RolledBackAt); err != nil {
+			return nil, err
+		}
+		index[g.ID] = len(generations)
+		generations = append(generations, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	files, err := s.queryGenerationFiles(fmt.Sprintf(`
+		SELECT f.generation_id, f.filepath, f.backuppath, f.status, f.prevpristine
+		FROM %s.generation_files f
+		JOIN %s.generation g ON g.id = f.generation_id
+		WHERE g.projectname = $1 AND g.connection = $2
+		ORDER BY f.filepath`, s.cfg.DBSchema, s.cfg.DBSchema), projectName, connName)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if i, ok := index[f.GenerationID]; ok {
+			generations[i].Files = append(generations[i].Files, f)
+		}
+	}
+	return generations, nil
+}
+
+// getGeneration loads a single generation run with its files.
+func (s *Server) getGeneration(id int) (models.Generation, error) {
+	var g models.Generation
+	err := s.db.QueryRow(fmt.Sprintf(`
+		SELECT id, projectname, connection, subsystem, tables, templates, generatedby, generatedat, rolledbackat
+		FROM %s.generation
+		WHERE id = $1`, s.cfg.DBSchema), id).Scan(
+		&g.ID, &g.ProjectName, &g.Connection, &g.Subsystem, &g.Tables, &g.Templates,
+		&g.GeneratedBy, &g.GeneratedAt, &g.RolledBackAt,
+	)
+	if err != nil {
+		return g, err
+	}
+
+	g.Files, err = s.queryGenerationFiles(fmt.Sprintf(`
+		SELECT generation_id, filepath, backuppath, status, prevpristine
+		FROM %s.generation_files
+		WHERE generation_id = $1
+		ORDER BY filepath`, s.cfg.DBSchema), id)
+	return g, err
+}
+
+func (s *Server) queryGenerationFiles(query string, args ...interface{}) ([]models.GenerationFile, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []models.GenerationFile
+	for rows.Next() {
+		var f models.GenerationFile
+		if err := rows.Scan(&f.GenerationID, &f.FilePath, &f.BackupPath, &f.Status, &f.PrevPristine); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// Rollback undoes a generation run: files it overwrote get their backup back
+// and files it created are removed. Runs are undone newest first, so a run
+// cannot be rolled back while a later, still active run wrote the same files.
+// Restored files get back the pristine copy of the run that wrote them, so
+// the next generation still merges their hand edits; removed files lose it.
+func (s *Server) Rollback(id int) (models.Generation, error) {
+	gen, err := s.getGeneration(id)
+	if err != nil {
+		return gen, err
+	}
+	if gen.RolledBackAt.Valid {
+		return gen, fmt.Errorf("generation %d was already rolled back", id)
+	}
+
+	var later []int
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT DISTINCT g.id
+		FROM %s.generation g
+		JOIN %s.generation_files f ON f.generation_id = g.id
+		WHERE g.id > $1 AND g.rolledbackat IS NULL
+			AND f.filepath IN (SELECT filepath FROM %s.generation_files WHERE generation_id = $1)
+		ORDER BY g.id`, s.cfg.DBSchema, s.cfg.DBSchema, s.cfg.DBSchema), id)
+	if err != nil {
+		return gen, err
+	}
+	for rows.Next() {
+		var laterID int
+		if err := rows.Scan(&laterID); err != nil {
+			rows.Close()
+			return gen, err
+		}
+		later = append(later, laterID)
+	}
+	rows.Close()
+	if len(later) > 0 {
+		return gen, fmt.Errorf("later generations %v rewrote files of this run; roll them back first", later)
+	}
+
+	// Check every backup first so a missing one leaves everything untouched
+	for _, f := range gen.Files {
+		if f.BackupPath.Valid {
+			if _, err := os.Stat(f.BackupPath.String); err != nil {
+				return gen, fmt.Errorf("backup of %s not found: %s", f.FilePath, f.BackupPath.String)
+			}
+		}
+	}
+
+	var manifest *generator.Manifest
+	if rootDir, err := s.getProjectRootDir(gen.ProjectName); err == nil {
+		manifest = generator.NewManifest(rootDir)
+	}
+
+	for _, f := range gen.Files {
+		if f.BackupPath.Valid {
+			err = os.Rename(f.BackupPath.String, f.FilePath)
+		} else {
+			err = os.Remove(f.FilePath)
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return gen, fmt.Errorf("failed to restore %s: %v", f.FilePath, err)
+		}
+		if manifest != nil {
+			if f.BackupPath.Valid && f.PrevPristine.Valid {
+				err = manifest.Record(f.FilePath, f.PrevPristine.String)
+			} else {
+				err = manifest.Forget(f.FilePath)
+			}
+			if err != nil {
+				return gen, err
+			}
+		}
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf(`UPDATE %s.generation SET rolledbackat = now() WHERE id = $1`, s.cfg.DBSchema), id)
+	return gen, err
+}
+
+// requestUser identifies who sent a request: the user set by an
+// authenticating proxy or basic auth, else the client address.
+func requestUser(r *http.Request) string {
+	for _, header := range []string{"X-Forwarded-User", "X-Remote-User"} {
+		if user := r.Header.Get(header); user != "" {
+			return user
+		}
+	}
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		return user
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -1,11 +1,17 @@
 package server
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"html/template"
 	"net/http"
+	"strings"
+	"time"
 
 	"api-scaffolding/internal/database"
+	"api-scaffolding/internal/models"
 )
 
 func (s *Server) handleGetInfoTables(w http.ResponseWriter, r *http.Request) {
@@ -16,176 +22,403 @@ func (s *Server) handleGetInfoTables(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 1. Get Connection Details
-	var c database.DatabaseConfig
-
-	row := s.db.QueryRow(fmt.Sprintf(`
-		SELECT dbtype, dbhost, dbport, dbuser, dbpass, dbname, dbschema, dbsslmode, dbtimezone 
-		FROM %s.dbconn 
-		WHERE projectname = $1 AND connection = $2`, s.cfg.DBSchema), projectName, connName)
+	diff, err := s.ScanConnection(r.Context(), projectName, connName)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
 
-	var dbType, dbHost, dbPort, dbUser, dbPass, dbNameNS, dbSchemaNS, dbSslMode, dbTimezone sql.NullString
-	if err := row.Scan(&dbType, &dbHost, &dbPort, &dbUser, &dbPass, &dbNameNS, &dbSchemaNS, &dbSslMode, &dbTimezone); err != nil {
+	tmpl, err := template.ParseFiles("templates/layout.html", "templates/introspection_summary.html")
+	if err != nil {
 		renderError(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	c.Driver = dbType.String
-	c.Host = dbHost.String
-	c.Port = dbPort.String
-	c.Username = dbUser.String
-	c.Password = dbPass.String
-	c.Database = dbNameNS.String
-	c.SSLMode = dbSslMode.String
-	c.Timezone = dbTimezone.String
+	data := struct {
+		ProjectName string
+		Connection  string
+		Diff        database.SchemaDiff
+	}{
+		ProjectName: projectName,
+		Connection:  connName,
+		Diff:        diff,
+	}
 
-	// 2. Connect to Target DB
-	scanner := database.NewScanner()
-	if err := scanner.Connect(&c); err != nil {
-		renderError(w, fmt.Errorf("failed to connect to target db: %v", err), http.StatusInternalServerError)
-		return
+	if err := tmpl.Execute(w, data); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
 	}
-	defer scanner.Disconnect()
+}
 
-	// 3. Get Tables
-	// Use the scanned schema
-	targetSchema := dbSchemaNS.String
-	if targetSchema == "" {
-		targetSchema = "public" // Default
+// ScanConnection introspects the target database of a connection and merges
+// the result into the stored metadata. New tables and columns are added,
+// dropped ones are removed and the database facts (type, nullability, default,
+// keys) are refreshed, while the curated settings (entity name, labels, help,
+// ordering, list/CRUD inclusion, length, audit flag) are kept. It returns the
+// changes found against the previously stored metadata. Cancelling ctx (the
+// client went away, SCAN_TIMEOUT expired) aborts the scan before anything is
+// stored.
+func (s *Server) ScanConnection(ctx context.Context, projectName, connName string) (database.SchemaDiff, error) {
+	// 1. Get Connection Details
+	conn, err := s.getConnection(projectName, connName)
+	if err != nil {
+		return database.SchemaDiff{}, err
 	}
 
-	tables, err := scanner.GetTables(targetSchema, []string{"*"})
+	// 2. Scan the target DB
+	tables, err := s.scanLive(ctx, conn)
 	if err != nil {
-		renderError(w, fmt.Errorf("failed to get tables: %v", err), http.StatusInternalServerError)
-		return
+		return database.SchemaDiff{}, err
 	}
 
-	// 4. Transaction to save metadata
-	tx, err := s.db.Begin()
+	// 3. Merge into the stored metadata
+	return s.mergeMetadata(conn, tables)
+}
+
+// scanLive connects to the target database of a connection and returns the
+// tables of its schemas. SCAN_WORKERS tables are read at the same time and
+// SCAN_TIMEOUT, when set, bounds the whole scan.
+func (s *Server) scanLive(ctx context.Context, conn models.DbConn) ([]database.Table, error) {
+	if s.cfg.ScanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.cfg.ScanTimeout)*time.Second)
+		defer cancel()
+	}
+
+	scanner, err := database.NewScanner(conn.DbType.String)
 	if err != nil {
-		renderError(w, err, http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	defer tx.Rollback()
+	config := dbConfig(conn)
+	config.Workers = s.cfg.ScanWorkers
+	if err := scanner.Connect(ctx, config); err != nil {
+		return nil, fmt.Errorf("failed to connect to target db: %v", err)
+	}
+	defer scanner.Disconnect()
 
-	// Delete existing for this connection to avoid dupes/stale data
-	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s.tables WHERE projectname=$1 AND connection=$2", s.cfg.DBSchema), projectName, connName)
+	tables, err := database.GetTablesIn(ctx, scanner, config.Schemas, []string{"*"})
 	if err != nil {
-		renderError(w, fmt.Errorf("failed to clear tables: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("failed to get tables: %v", err)
 	}
-	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s.tablesfields WHERE projectname=$1 AND connection=$2", s.cfg.DBSchema), projectName, connName)
+	return tables, nil
+}
+
+// mergeMetadata applies a fresh scan of the schemas of a connection to its
+// stored metadata inside a single transaction. Whatever is stored for a
+// schema no longer scanned goes away with it.
+func (s *Server) mergeMetadata(conn models.DbConn, tables []database.Table) (database.SchemaDiff, error) {
+	stored, metadata, err := s.loadStoredTables(conn)
 	if err != nil {
-		renderError(w, fmt.Errorf("failed to clear tablesfields: %v", err), http.StatusInternalServerError)
-		return
+		return database.SchemaDiff{}, fmt.Errorf("failed to load stored metadata: %v", err)
 	}
+	diff := database.DiffTables(stored, tables)
 
-	// Delete rels
-	// Note: using dbNameNS.String not dbName
-	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s.tablesrels WHERE connection=$1 AND dbname=$2 AND dbschema=$3", s.cfg.DBSchema), connName, dbNameNS.String, targetSchema)
+	tx, err := s.db.Begin()
 	if err != nil {
-		renderError(w, fmt.Errorf("failed to clear tablesrels: %v", err), http.StatusInternalServerError)
-		return
+		return database.SchemaDiff{}, err
 	}
+	defer tx.Rollback()
 
+	storedByKey := make(map[string]database.Table, len(stored))
+	for _, t := range stored {
+		storedByKey[database.TableKey(t.Schema, t.Name)] = t
+	}
+	liveByKey := make(map[string]bool, len(tables))
 	for _, t := range tables {
-		// Insert Table
-		_, err := tx.Exec(fmt.Sprintf(`
-			INSERT INTO %s.tables (projectname, connection, dbname, dbschema, tablename, entityname, detail)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)`, s.cfg.DBSchema),
-			projectName, connName, dbNameNS.String, targetSchema, t.Name, t.Name, t.Comment)
-		if err != nil {
-			renderError(w, fmt.Errorf("failed to insert table %s: %v", t.Name, err), http.StatusInternalServerError)
-			return
+		liveByKey[database.TableKey(t.Schema, t.Name)] = true
+	}
+
+	// Tables dropped from the database
+	for _, t := range stored {
+		if liveByKey[database.TableKey(t.Schema, t.Name)] {
+			continue
 		}
+		for _, table := range []string{"tablesfields", "tables"} {
+			_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s.%s WHERE projectname=$1 AND connection=$2 AND dbschema=$3 AND tablename=$4", s.cfg.DBSchema, table),
+				conn.ProjectName, conn.Connection, t.Schema, t.Name)
+			if err != nil {
+				return database.SchemaDiff{}, fmt.Errorf("failed to remove table %s: %v", t.Name, err)
+			}
+		}
+	}
 
-		// Insert Fields
-		for i, col := range t.Columns {
-			isNull := "0"
-			if col.IsNullable {
-				isNull = "1"
+	for _, t := range tables {
+		old, exists := storedByKey[database.TableKey(t.Schema, t.Name)]
+		if !exists {
+			_, err := tx.Exec(fmt.Sprintf(`
+				INSERT INTO %s.tables (projectname, connection, dbname, dbschema, tablename, entityname, detail, isview)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, s.cfg.DBSchema),
+				conn.ProjectName, conn.Connection, conn.DbName.String, t.Schema, t.Name, t.Name, t.Comment, t.IsView)
+			if err != nil {
+				return database.SchemaDiff{}, fmt.Errorf("failed to insert table %s: %v", t.Name, err)
 			}
-			pk := ""
-			// Check if PK
-			isPk := false
-			for _, p := range t.PrimaryKeys {
-				if p == col.Name {
-					isPk = true
-					break
+			for i, col := range t.Columns {
+				if err := s.insertField(tx, conn, t, col, i+1); err != nil {
+					return database.SchemaDiff{}, err
 				}
 			}
-			if isPk {
-				pk = "1"
-			}
+			continue
+		}
 
-			// Check FK
-			var fTable, fKey sql.NullString
-			for _, fk := range t.ForeignKeys {
-				if fk.ColumnName == col.Name {
-					fTable = sql.NullString{String: fk.ReferencedTable, Valid: true}
-					fKey = sql.NullString{String: fk.ReferencedColumn, Valid: true}
-					break
-				}
-			}
+		_, err := tx.Exec(fmt.Sprintf(`
+			UPDATE %s.tables SET isview = $5
+			WHERE projectname=$1 AND connection=$2 AND dbschema=$3 AND tablename=$4`, s.cfg.DBSchema),
+			conn.ProjectName, conn.Connection, t.Schema, t.Name, t.IsView)
+		if err != nil {
+			return database.SchemaDiff{}, fmt.Errorf("failed to update table %s: %v", t.Name, err)
+		}
 
-			// Default Value
-			var defVal sql.NullString
-			if col.DefaultValue != nil {
-				defVal = sql.NullString{String: *col.DefaultValue, Valid: true}
+		// Only fill the detail when nobody wrote one yet
+		if t.Comment != "" {
+			_, err := tx.Exec(fmt.Sprintf(`
+				UPDATE %s.tables SET detail = $5
+				WHERE projectname=$1 AND connection=$2 AND dbschema=$3 AND tablename=$4
+					AND (detail IS NULL OR detail = '' OR detail = '-')`, s.cfg.DBSchema),
+				conn.ProjectName, conn.Connection, t.Schema, t.Name, t.Comment)
+			if err != nil {
+				return database.SchemaDiff{}, fmt.Errorf("failed to update table %s: %v", t.Name, err)
 			}
+		}
 
-			valLength := ""
-			if col.MaxLength != nil {
-				valLength = fmt.Sprintf("%d", *col.MaxLength)
+		oldCols := make(map[string]bool, len(old.Columns))
+		for _, col := range old.Columns {
+			oldCols[strings.ToLower(col.Name)] = true
+		}
+		liveCols := make(map[string]bool, len(t.Columns))
+
+		// New columns go after the ones already ordered by the user
+		nextOrder := 0
+		for _, fm := range metadata[database.TableKey(t.Schema, t.Name)].Fields {
+			if fm.OrderList > nextOrder {
+				nextOrder = fm.OrderList
 			}
+		}
 
-			// Defaults
-			inList := 1
-			inCrud := 1
+		for _, col := range t.Columns {
+			liveCols[strings.ToLower(col.Name)] = true
+			if !oldCols[strings.ToLower(col.Name)] {
+				nextOrder++
+				if err := s.insertField(tx, conn, t, col, nextOrder); err != nil {
+					return database.SchemaDiff{}, err
+				}
+				continue
+			}
 
+			// Matched ignoring case, so a column renamed only in case keeps
+			// its curated settings and takes the new name
+			facts := newFieldFacts(t, col)
 			_, err := tx.Exec(fmt.Sprintf(`
-				INSERT INTO %s.tablesfields (
-					projectname, connection, dbname, dbschema, tablename, fieldname, 
-					typename, defaultvalue, is_null, pk, unq, 
-					ftable, fkey, label, labelhelp, orderlist, 
-					inlist, incrud, val_length, detail
-				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`, s.cfg.DBSchema),
-				projectName, connName, dbNameNS.String, targetSchema, t.Name, col.Name,
-				col.DataType, defVal, isNull, pk, "", // unq set empty
-				fTable, fKey, col.Name, col.Name, i+1,
-				inList, inCrud, valLength, col.Comment,
+				UPDATE %s.tablesfields SET
+					fieldname = $25, dbname = $5, typename = $6, defaultvalue = $7, is_null = $8, pk = $9, unq = $10, ftable = $11, fkey = $12,
+					chk_enum = $13, chk_min = $14, chk_max = $15, chk_pattern = $16, chk_length = $17,
+					ordinal = $18, is_identity = $19, is_generated = $20,
+					elementtype = $21, domainname = $22, num_precision = $23, num_scale = $24
+				WHERE projectname=$1 AND connection=$2 AND dbschema=$3 AND tablename=$4 AND lower(fieldname)=lower($25)`, s.cfg.DBSchema),
+				conn.ProjectName, conn.Connection, t.Schema, t.Name,
+				conn.DbName.String, col.DataType, facts.DefaultValue, facts.IsNull, facts.Pk, facts.Unq, facts.FTable, facts.FKey,
+				facts.ChkEnum, facts.ChkMin, facts.ChkMax, facts.ChkPattern, facts.ChkLength,
+				facts.Ordinal, facts.IsIdentity, facts.IsGenerated,
+				facts.ElementType, facts.DomainName, facts.NumPrecision, facts.NumScale,
+				col.Name,
 			)
 			if err != nil {
-				renderError(w, fmt.Errorf("failed to insert field %s.%s: %v", t.Name, col.Name, err), http.StatusInternalServerError)
-				return
+				return database.SchemaDiff{}, fmt.Errorf("failed to update field %s.%s: %v", t.Name, col.Name, err)
+			}
+		}
+
+		// Columns dropped from the database
+		for _, col := range old.Columns {
+			if liveCols[strings.ToLower(col.Name)] {
+				continue
+			}
+			_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s.tablesfields WHERE projectname=$1 AND connection=$2 AND dbschema=$3 AND tablename=$4 AND fieldname=$5", s.cfg.DBSchema),
+				conn.ProjectName, conn.Connection, t.Schema, t.Name, col.Name)
+			if err != nil {
+				return database.SchemaDiff{}, fmt.Errorf("failed to remove field %s.%s: %v", t.Name, col.Name, err)
 			}
 		}
+	}
 
-		// Insert Rels
+	// Relations and indexes hold no curated data: rebuild them from the scan,
+	// in every schema so those dropped from the connection leave nothing
+	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s.tablesrels WHERE connection=$1 AND dbname=$2", s.cfg.DBSchema), conn.Connection, conn.DbName.String)
+	if err != nil {
+		return database.SchemaDiff{}, fmt.Errorf("failed to clear tablesrels: %v", err)
+	}
+	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s.tablesindexes WHERE projectname=$1 AND connection=$2", s.cfg.DBSchema), conn.ProjectName, conn.Connection)
+	if err != nil {
+		return database.SchemaDiff{}, fmt.Errorf("failed to clear tablesindexes: %v", err)
+	}
+
+	for _, t := range tables {
 		for _, fk := range t.ForeignKeys {
 			// typeRel defaults to 1:N
 			typeRel := "1:N"
 
+			// One row per column of the key
+			for k, column := range fk.Columns {
+				_, err := tx.Exec(fmt.Sprintf(`
+					INSERT INTO %s.tablesrels (
+						connection, dbname, dbschema, tablename,
+						typerel, fname, tabler, fnamepk, is_null,
+						constraintname, position, schemar
+					) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`, s.cfg.DBSchema),
+					conn.Connection, conn.DbName.String, t.Schema, t.Name,
+					typeRel, column, fk.ReferencedTable, fk.ReferencedColumnOf(column), 1,
+					fk.ConstraintName, k+1, fk.ReferencedSchema,
+				)
+				if err != nil {
+					return database.SchemaDiff{}, fmt.Errorf("failed to insert rel %s->%s: %v", t.Name, fk.ReferencedTable, err)
+				}
+			}
+		}
+	}
+
+	for _, t := range tables {
+		for _, idx := range t.Indexes {
 			_, err := tx.Exec(fmt.Sprintf(`
-				INSERT INTO %s.tablesrels (
-					connection, dbname, dbschema, tablename, 
-					typerel, fname, tabler, fnamepk, is_null
-				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, s.cfg.DBSchema),
-				connName, dbNameNS.String, targetSchema, t.Name,
-				typeRel, fk.ColumnName, fk.ReferencedTable, fk.ReferencedColumn, 1,
+				INSERT INTO %s.tablesindexes (projectname, connection, dbschema, tablename, indexname, columns, isunique, isprimary)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, s.cfg.DBSchema),
+				conn.ProjectName, conn.Connection, t.Schema, t.Name, idx.Name, strings.Join(idx.Columns, ","), idx.Unique, idx.Primary,
 			)
 			if err != nil {
-				renderError(w, fmt.Errorf("failed to insert rel %s->%s: %v", t.Name, fk.ReferencedTable, err), http.StatusInternalServerError)
-				return
+				return database.SchemaDiff{}, fmt.Errorf("failed to insert index %s of %s: %v", idx.Name, t.Name, err)
 			}
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		renderError(w, err, http.StatusInternalServerError)
-		return
+		return database.SchemaDiff{}, err
+	}
+
+	return diff, nil
+}
+
+// fieldFacts are the tablesfields values that come from the database itself
+// and are refreshed on every scan.
+type fieldFacts struct {
+	IsNull       string
+	Pk           string
+	Unq          string
+	FTable       sql.NullString
+	FKey         sql.NullString
+	DefaultValue sql.NullString
+	ChkLength    sql.NullString
+	ChkEnum      sql.NullString
+	ChkMin       sql.NullString
+	ChkMax       sql.NullString
+	ChkPattern   sql.NullString
+	Ordinal      sql.NullInt32
+	IsIdentity   string
+	IsGenerated  string
+	ElementType  sql.NullString
+	DomainName   sql.NullString
+	NumPrecision sql.NullInt32
+	NumScale     sql.NullInt32
+}
+
+func newFieldFacts(t database.Table, col database.Column) fieldFacts {
+	facts := fieldFacts{IsNull: "0"}
+	if col.IsNullable {
+		facts.IsNull = "1"
 	}
 
-	// Redirect back to connection list
-	http.Redirect(w, r, fmt.Sprintf("/connections/tables?projectname=%s&connection=%s", projectName, connName), http.StatusSeeOther)
+	// Check if PK
+	for _, p := range t.PrimaryKeys {
+		if p == col.Name {
+			facts.Pk = "1"
+			break
+		}
+	}
+
+	if t.IsUniqueColumn(col.Name) {
+		facts.Unq = "1"
+	}
+
+	// Check FK
+	if fk, ok := t.ForeignKeyOf(col.Name); ok {
+		facts.FTable = sql.NullString{String: fk.QualifiedReferencedTable(t.Schema), Valid: true}
+		facts.FKey = sql.NullString{String: fk.ReferencedColumnOf(col.Name), Valid: true}
+	}
+
+	// Default Value
+	if col.DefaultValue != nil {
+		facts.DefaultValue = sql.NullString{String: *col.DefaultValue, Valid: true}
+	}
+
+	if col.MaxLength != nil {
+		facts.ChkLength = sql.NullString{String: fmt.Sprintf("%d", *col.MaxLength), Valid: true}
+	}
+
+	// CHECK constraints and enum types
+	if len(col.Enum) > 0 {
+		values, _ := json.Marshal(col.Enum)
+		facts.ChkEnum = sql.NullString{String: string(values), Valid: true}
+	}
+	if col.Min != nil {
+		facts.ChkMin = sql.NullString{String: database.FormatBound(*col.Min), Valid: true}
+	}
+	if col.Max != nil {
+		facts.ChkMax = sql.NullString{String: database.FormatBound(*col.Max), Valid: true}
+	}
+	if col.Pattern != "" {
+		facts.ChkPattern = sql.NullString{String: col.Pattern, Valid: true}
+	}
+
+	// Position and columns written by the database
+	if col.Position > 0 {
+		facts.Ordinal = sql.NullInt32{Int32: int32(col.Position), Valid: true}
+	}
+	if col.IsIdentity {
+		facts.IsIdentity = "1"
+	}
+	if col.IsGenerated {
+		facts.IsGenerated = "1"
+	}
+
+	// Declared type details
+	if col.ElementType != "" {
+		facts.ElementType = sql.NullString{String: col.ElementType, Valid: true}
+	}
+	if col.Domain != "" {
+		facts.DomainName = sql.NullString{String: col.Domain, Valid: true}
+	}
+	if col.Precision != nil {
+		facts.NumPrecision = sql.NullInt32{Int32: int32(*col.Precision), Valid: true}
+	}
+	if col.Scale != nil {
+		facts.NumScale = sql.NullInt32{Int32: int32(*col.Scale), Valid: true}
+	}
+	return facts
+}
+
+// insertField stores a new column with the default curated settings.
+func (s *Server) insertField(tx *sql.Tx, conn models.DbConn, t database.Table, col database.Column, order int) error {
+	facts := newFieldFacts(t, col)
+
+	// Defaults
+	inList := 1
+	inCrud := 1
+
+	_, err := tx.Exec(fmt.Sprintf(`
+		INSERT INTO %s.tablesfields (
+			projectname, connection, dbname, dbschema, tablename, fieldname,
+			typename, defaultvalue, is_null, pk, unq,
+			ftable, fkey, label, labelhelp, orderlist,
+			inlist, incrud, detail,
+			chk_enum, chk_min, chk_max, chk_pattern, chk_length,
+			ordinal, is_identity, is_generated,
+			elementtype, domainname, num_precision, num_scale
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31)`, s.cfg.DBSchema),
+		conn.ProjectName, conn.Connection, conn.DbName.String, t.Schema, t.Name, col.Name,
+		col.DataType, facts.DefaultValue, facts.IsNull, facts.Pk, facts.Unq,
+		facts.FTable, facts.FKey, col.Name, col.Name, order,
+		inList, inCrud, col.Comment,
+		facts.ChkEnum, facts.ChkMin, facts.ChkMax, facts.ChkPattern, facts.ChkLength,
+		facts.Ordinal, facts.IsIdentity, facts.IsGenerated,
+		facts.ElementType, facts.DomainName, facts.NumPrecision, facts.NumScale,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert field %s.%s: %v", t.Name, col.Name, err)
+	}
+	return nil
 }
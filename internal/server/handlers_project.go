@@ -5,17 +5,22 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"strings"
 
+	"api-scaffolding/internal/generator"
 	"api-scaffolding/internal/models"
 )
 
+// rulePacksDir holds the validation rule packs, <name>.json.
+const rulePacksDir = "rulepacks"
+
 func (s *Server) handleProjectsList(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
 
-	rows, err := s.db.Query(fmt.Sprintf("SELECT projectname, envdir, rootdir, maindir, modeldir, actiondir, testdir FROM %s.project ORDER BY projectname", s.cfg.DBSchema))
+	rows, err := s.db.Query(fmt.Sprintf("SELECT projectname, envdir, rootdir, maindir, modeldir, actiondir, testdir, rulepacks FROM %s.project ORDER BY projectname", s.cfg.DBSchema))
 	if err != nil {
 		renderError(w, err, http.StatusInternalServerError)
 		return
@@ -25,7 +30,7 @@ func (s *Server) handleProjectsList(w http.ResponseWriter, r *http.Request) {
 	var projects []models.Project
 	for rows.Next() {
 		var p models.Project
-		if err := rows.Scan(&p.ProjectName, &p.EnvDir, &p.RootDir, &p.MainDir, &p.ModelDir, &p.ActionDir, &p.TestDir); err != nil {
+		if err := rows.Scan(&p.ProjectName, &p.EnvDir, &p.RootDir, &p.MainDir, &p.ModelDir, &p.ActionDir, &p.TestDir, &p.RulePacks); err != nil {
 			renderError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -44,15 +49,7 @@ func (s *Server) handleProjectsList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleProjectNew(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles("templates/layout.html", "templates/project_form.html")
-	if err != nil {
-		renderError(w, err, http.StatusInternalServerError)
-		return
-	}
-
-	if err := tmpl.Execute(w, &models.Project{}); err != nil {
-		renderError(w, err, http.StatusInternalServerError)
-	}
+	s.renderProjectForm(w, models.Project{})
 }
 
 func (s *Server) handleProjectEdit(w http.ResponseWriter, r *http.Request) {
@@ -62,9 +59,9 @@ func (s *Server) handleProjectEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	row := s.db.QueryRow(fmt.Sprintf("SELECT projectname, envdir, rootdir, maindir, modeldir, actiondir, testdir FROM %s.project WHERE projectname = $1", s.cfg.DBSchema), projectName)
+	row := s.db.QueryRow(fmt.Sprintf("SELECT projectname, envdir, rootdir, maindir, modeldir, actiondir, testdir, rulepacks FROM %s.project WHERE projectname = $1", s.cfg.DBSchema), projectName)
 	var p models.Project
-	if err := row.Scan(&p.ProjectName, &p.EnvDir, &p.RootDir, &p.MainDir, &p.ModelDir, &p.ActionDir, &p.TestDir); err != nil {
+	if err := row.Scan(&p.ProjectName, &p.EnvDir, &p.RootDir, &p.MainDir, &p.ModelDir, &p.ActionDir, &p.TestDir, &p.RulePacks); err != nil {
 		if err == sql.ErrNoRows {
 			http.NotFound(w, r)
 			return
@@ -73,13 +70,34 @@ func (s *Server) handleProjectEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.renderProjectForm(w, p)
+}
+
+// renderProjectForm shows the project form with the rule packs available.
+func (s *Server) renderProjectForm(w http.ResponseWriter, p models.Project) {
+	packs, err := generator.ListRulePacks(rulePacksDir)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
 	tmpl, err := template.ParseFiles("templates/layout.html", "templates/project_form.html")
 	if err != nil {
 		renderError(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	if err := tmpl.Execute(w, p); err != nil {
+	data := struct {
+		models.Project
+		AvailableRulePacks []generator.RulePack
+		DefaultRulePacks   string
+	}{
+		Project:            p,
+		AvailableRulePacks: packs,
+		DefaultRulePacks:   strings.Join(generator.DefaultRulePacks, ", "),
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
 		renderError(w, err, http.StatusInternalServerError)
 	}
 }
@@ -99,12 +117,20 @@ func (s *Server) handleProjectSave(w http.ResponseWriter, r *http.Request) {
 	testDir := r.FormValue("testdir")
 	isNew := r.FormValue("is_new") == "true"
 
+	// Every selected rule pack must load; empty keeps the defaults and
+	// "none" turns them all off
+	rulePacks := nullIfEmpty(strings.Join(generator.SplitRulePacks(r.FormValue("rulepacks")), ", "))
+	if _, err := generator.LoadValidationRules(rulePacksDir, generator.SelectedRulePacks(rulePacks.String)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var err error
 	if isNew {
 		_, err = s.db.Exec(fmt.Sprintf(`
-			INSERT INTO %s.project (projectname, envdir, rootdir, maindir, modeldir, actiondir, testdir)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)`, s.cfg.DBSchema),
-			projectName, envDir, rootDir, mainDir, modelDir, actionDir, testDir)
+			INSERT INTO %s.project (projectname, envdir, rootdir, maindir, modeldir, actiondir, testdir, rulepacks)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, s.cfg.DBSchema),
+			projectName, envDir, rootDir, mainDir, modelDir, actionDir, testDir, rulePacks)
 		if err == nil {
 			// Auto-create the default "public" subsystem for every new project.
 			s.db.Exec(fmt.Sprintf(`
@@ -116,9 +142,9 @@ func (s *Server) handleProjectSave(w http.ResponseWriter, r *http.Request) {
 	} else {
 		_, err = s.db.Exec(fmt.Sprintf(`
 			UPDATE %s.project 
-			SET envdir=$2, rootdir=$3, maindir=$4, modeldir=$5, actiondir=$6, testdir=$7
+			SET envdir=$2, rootdir=$3, maindir=$4, modeldir=$5, actiondir=$6, testdir=$7, rulepacks=$8
 			WHERE projectname=$1`, s.cfg.DBSchema),
-			projectName, envDir, rootDir, mainDir, modelDir, actionDir, testDir)
+			projectName, envDir, rootDir, mainDir, modelDir, actionDir, testDir, rulePacks)
 	}
 
 	if err != nil {
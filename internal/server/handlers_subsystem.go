@@ -205,3 +205,25 @@ func (s *Server) handleSubsystemDelete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// listSubsystems returns the subsystems of a project ordered by name.
+func (s *Server) listSubsystems(projectName string) ([]models.Subsystem, error) {
+	rows, err := s.db.Query(
+		fmt.Sprintf("SELECT projectname, subsystem, details FROM %s.subsystem WHERE projectname = $1 ORDER BY subsystem", s.cfg.DBSchema),
+		projectName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subsystems []models.Subsystem
+	for rows.Next() {
+		var sub models.Subsystem
+		if err := rows.Scan(&sub.ProjectName, &sub.Subsystem, &sub.Details); err != nil {
+			return nil, err
+		}
+		subsystems = append(subsystems, sub)
+	}
+	return subsystems, rows.Err()
+}
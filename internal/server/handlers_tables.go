@@ -16,48 +16,18 @@ func (s *Server) handleTablesList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := s.db.Query(fmt.Sprintf(`
-		SELECT projectname, connection, dbname, dbschema, tablename, entityname, detail 
-		FROM %s.tables 
-		WHERE projectname = $1 AND connection = $2 ORDER BY tablename`, s.cfg.DBSchema), projectName, connName)
+	tables, err := s.ListTables(projectName, connName)
 	if err != nil {
 		renderError(w, err, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var tables []models.Table
-	for rows.Next() {
-		var t models.Table
-		if err := rows.Scan(
-			&t.ProjectName, &t.Connection, &t.DbName, &t.DbSchema, &t.TableName, &t.EntityName, &t.Detail,
-		); err != nil {
-			renderError(w, err, http.StatusInternalServerError)
-			return
-		}
-		tables = append(tables, t)
-	}
 
 	// Fetch subsystems for this project
-	subRows, err := s.db.Query(
-		fmt.Sprintf("SELECT projectname, subsystem, details FROM %s.subsystem WHERE projectname = $1 ORDER BY subsystem", s.cfg.DBSchema),
-		projectName,
-	)
+	subsystems, err := s.listSubsystems(projectName)
 	if err != nil {
 		renderError(w, err, http.StatusInternalServerError)
 		return
 	}
-	defer subRows.Close()
-
-	var subsystems []models.Subsystem
-	for subRows.Next() {
-		var sub models.Subsystem
-		if err := subRows.Scan(&sub.ProjectName, &sub.Subsystem, &sub.Details); err != nil {
-			renderError(w, err, http.StatusInternalServerError)
-			return
-		}
-		subsystems = append(subsystems, sub)
-	}
 
 	tmpl, err := template.ParseFiles("templates/layout.html", "templates/tables_list.html")
 	if err != nil {
@@ -81,3 +51,27 @@ func (s *Server) handleTablesList(w http.ResponseWriter, r *http.Request) {
 		renderError(w, err, http.StatusInternalServerError)
 	}
 }
+
+// ListTables returns the tables stored in the meta schema for a connection.
+func (s *Server) ListTables(projectName, connName string) ([]models.Table, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT projectname, connection, dbname, dbschema, tablename, entityname, detail, isview
+		FROM %s.tables 
+		WHERE projectname = $1 AND connection = $2 ORDER BY tablename`, s.cfg.DBSchema), projectName, connName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []models.Table
+	for rows.Next() {
+		var t models.Table
+		if err := rows.Scan(
+			&t.ProjectName, &t.Connection, &t.DbName, &t.DbSchema, &t.TableName, &t.EntityName, &t.Detail, &t.IsView,
+		); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"api-scaffolding/internal/generator"
+	"api-scaffolding/internal/models"
+)
+
+func (s *Server) handleTypeMapsList(w http.ResponseWriter, r *http.Request) {
+	projectName := r.URL.Query().Get("projectname")
+	if projectName == "" {
+		http.Error(w, "projectname is required", http.StatusBadRequest)
+		return
+	}
+
+	typeMaps, err := s.listTypeMaps(projectName)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := template.ParseFiles("templates/layout.html", "templates/typemaps_list.html")
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		ProjectName string
+		TypeMaps    []models.TypeMap
+	}{
+		ProjectName: projectName,
+		TypeMaps:    typeMaps,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleTypeMapNew(w http.ResponseWriter, r *http.Request) {
+	projectName := r.URL.Query().Get("projectname")
+	if projectName == "" {
+		http.Error(w, "projectname is required", http.StatusBadRequest)
+		return
+	}
+	s.renderTypeMapForm(w, projectName, nil)
+}
+
+func (s *Server) handleTypeMapEdit(w http.ResponseWriter, r *http.Request) {
+	projectName := r.URL.Query().Get("projectname")
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if projectName == "" || err != nil {
+		http.Error(w, "projectname and id are required", http.StatusBadRequest)
+		return
+	}
+
+	row := s.db.QueryRow(
+		fmt.Sprintf("SELECT id, projectname, dbtype, spectype, format, validation, orderlist FROM %s.typemaps WHERE projectname = $1 AND id = $2", s.cfg.DBSchema),
+		projectName, id,
+	)
+	var tm models.TypeMap
+	if err := row.Scan(&tm.ID, &tm.ProjectName, &tm.DbType, &tm.SpecType, &tm.Format, &tm.Validation, &tm.OrderList); err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+	s.renderTypeMapForm(w, projectName, &tm)
+}
+
+func (s *Server) renderTypeMapForm(w http.ResponseWriter, projectName string, tm *models.TypeMap) {
+	tmpl, err := template.ParseFiles("templates/layout.html", "templates/typemap_form.html")
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		ProjectName string
+		TypeMap     *models.TypeMap
+	}{
+		ProjectName: projectName,
+		TypeMap:     tm,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleTypeMapSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectName := r.FormValue("projectname")
+	dbType := strings.TrimSpace(r.FormValue("dbtype"))
+	specType := strings.TrimSpace(r.FormValue("spectype"))
+	format := strings.TrimSpace(r.FormValue("format"))
+	validation := strings.TrimSpace(r.FormValue("validation"))
+	orderList, _ := strconv.Atoi(r.FormValue("orderlist"))
+
+	if projectName == "" {
+		http.Error(w, "projectname is required", http.StatusBadRequest)
+		return
+	}
+	// The rule is checked as the generator will use it
+	if _, err := generator.NewTypeMap(dbType, specType, format, validation); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if id, convErr := strconv.Atoi(r.FormValue("id")); convErr == nil && id > 0 {
+		_, err = s.db.Exec(
+			fmt.Sprintf("UPDATE %s.typemaps SET dbtype = $3, spectype = $4, format = $5, validation = $6, orderlist = $7 WHERE projectname = $1 AND id = $2", s.cfg.DBSchema),
+			projectName, id, dbType, specType, nullIfEmpty(format), nullIfEmpty(validation), orderList,
+		)
+	} else {
+		_, err = s.db.Exec(
+			fmt.Sprintf("INSERT INTO %s.typemaps (projectname, dbtype, spectype, format, validation, orderlist) VALUES ($1, $2, $3, $4, $5, $6)", s.cfg.DBSchema),
+			projectName, dbType, specType, nullIfEmpty(format), nullIfEmpty(validation), orderList,
+		)
+	}
+
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/typemaps?projectname="+url.QueryEscape(projectName), http.StatusSeeOther)
+}
+
+func (s *Server) handleTypeMapDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectName := r.FormValue("projectname")
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if projectName == "" || err != nil {
+		http.Error(w, "projectname and id are required", http.StatusBadRequest)
+		return
+	}
+
+	_, err = s.db.Exec(
+		fmt.Sprintf("DELETE FROM %s.typemaps WHERE projectname = $1 AND id = $2", s.cfg.DBSchema),
+		projectName, id,
+	)
+	if err != nil {
+		renderError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// listTypeMaps returns the type mapping rules of a project in the order the
+// generator tries them.
+func (s *Server) listTypeMaps(projectName string) ([]models.TypeMap, error) {
+	rows, err := s.db.Query(
+		fmt.Sprintf("SELECT id, projectname, dbtype, spectype, format, validation, orderlist FROM %s.typemaps WHERE projectname = $1 ORDER BY orderlist, id", s.cfg.DBSchema),
+		projectName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var typeMaps []models.TypeMap
+	for rows.Next() {
+		var tm models.TypeMap
+		if err := rows.Scan(&tm.ID, &tm.ProjectName, &tm.DbType, &tm.SpecType, &tm.Format, &tm.Validation, &tm.OrderList); err != nil {
+			return nil, err
+		}
+		typeMaps = append(typeMaps, tm)
+	}
+	return typeMaps, rows.Err()
+}
+
+// loadTypeMaps returns the type mapping rules of a project compiled for the
+// generator.
+func (s *Server) loadTypeMaps(projectName string) ([]generator.TypeMap, error) {
+	stored, err := s.listTypeMaps(projectName)
+	if err != nil {
+		return nil, err
+	}
+	typeMaps := make([]generator.TypeMap, 0, len(stored))
+	for _, tm := range stored {
+		rule, err := generator.NewTypeMap(tm.DbType, tm.SpecType, tm.Format.String, tm.Validation.String)
+		if err != nil {
+			return nil, fmt.Errorf("type mapping %d: %v", tm.ID, err)
+		}
+		typeMaps = append(typeMaps, rule)
+	}
+	return typeMaps, nil
+}
+
+// nullIfEmpty stores an empty form value as NULL.
+func nullIfEmpty(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"api-scaffolding/internal/database"
+	"api-scaffolding/internal/generator"
+	"api-scaffolding/internal/models"
+)
+
+// listTableFields returns the tablesfields rows of a connection ordered by
+// table and orderlist. An empty tableName returns the fields of every table.
+func (s *Server) listTableFields(projectName, connName, schema, tableName string) ([]models.TableField, error) {
+	query := fmt.Sprintf(`
+		SELECT projectname, connection, dbname, dbschema, tablename, fieldname,
+			typename, defaultvalue, is_null, pk, unq,
+			ftable, fkey, label, labelhelp, orderlist,
+			inlist, incrud, val_length, auditoria, detail,
+			chk_enum, chk_min, chk_max, chk_pattern, chk_length,
+			ordinal, is_identity, is_generated,
+			elementtype, domainname, num_precision, num_scale,
+			val_min, val_max, val_pattern, val_enum, val_message
+		FROM %s.tablesfields
+		WHERE projectname = $1 AND connection = $2 AND ($4 = '' OR (dbschema = $3 AND tablename = $4))
+		ORDER BY dbschema, tablename, orderlist, fieldname`, s.cfg.DBSchema)
+
+	rows, err := s.db.Query(query, projectName, connName, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []models.TableField
+	for rows.Next() {
+		var f models.TableField
+		if err := rows.Scan(
+			&f.ProjectName, &f.Connection, &f.DbName, &f.DbSchema, &f.TableName, &f.FieldName,
+			&f.TypeName, &f.DefaultValue, &f.IsNull, &f.Pk, &f.Unq,
+			&f.FTable, &f.FKey, &f.Label, &f.LabelHelp, &f.OrderList,
+			&f.InList, &f.InCrud, &f.ValLength, &f.Auditoria, &f.Detail,
+			&f.ChkEnum, &f.ChkMin, &f.ChkMax, &f.ChkPattern, &f.ChkLength,
+			&f.Ordinal, &f.IsIdentity, &f.IsGenerated,
+			&f.ElementType, &f.DomainName, &f.NumPrecision, &f.NumScale,
+			&f.ValMin, &f.ValMax, &f.ValPattern, &f.ValEnum, &f.ValMessage,
+		); err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+// listTableRels returns the tablesrels rows stored for a connection.
+func (s *Server) listTableRels(conn models.DbConn) ([]models.TableRel, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT connection, dbname, dbschema, tablename, typerel, fname, tabler, fnamepk, is_null, constraintname, position, schemar
+		FROM %s.tablesrels
+		WHERE connection = $1 AND dbname = $2
+		ORDER BY dbschema, tablename, constraintname, position, fname`, s.cfg.DBSchema), conn.Connection, conn.DbName.String)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rels []models.TableRel
+	for rows.Next() {
+		var r models.TableRel
+		if err := rows.Scan(&r.Connection, &r.DbName, &r.DbSchema, &r.TableName, &r.TypeRel, &r.FName, &r.TableR, &r.FNamePk, &r.IsNull, &r.ConstraintName, &r.Position, &r.SchemaR); err != nil {
+			return nil, err
+		}
+		rels = append(rels, r)
+	}
+	return rels, rows.Err()
+}
+
+// listTableIndexes returns the tablesindexes rows stored for a connection.
+func (s *Server) listTableIndexes(conn models.DbConn) ([]models.TableIndex, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT projectname, connection, dbschema, tablename, indexname, columns, isunique, isprimary
+		FROM %s.tablesindexes
+		WHERE projectname = $1 AND connection = $2
+		ORDER BY tablename, indexname`, s.cfg.DBSchema), conn.ProjectName, conn.Connection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []models.TableIndex
+	for rows.Next() {
+		var idx models.TableIndex
+		if err := rows.Scan(&idx.ProjectName, &idx.Connection, &idx.DbSchema, &idx.TableName, &idx.IndexName, &idx.Columns, &idx.IsUnique, &idx.IsPrimary); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, rows.Err()
+}
+
+// loadStoredTables rebuilds the tables of a connection from the metadata
+// stored by "Get info tables", together with the curated settings (labels,
+// ordering, list/CRUD inclusion, entity names) the generator applies on top.
+// The metadata is keyed by database.TableKey, as the same name can exist in
+// several of the scanned schemas.
+func (s *Server) loadStoredTables(conn models.DbConn) ([]database.Table, map[string]generator.TableMeta, error) {
+	storedTables, err := s.ListTables(conn.ProjectName, conn.Connection)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, err := s.listTableFields(conn.ProjectName, conn.Connection, "", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	rels, err := s.listTableRels(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexes, err := s.listTableIndexes(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tables := make([]database.Table, 0, len(storedTables))
+	metadata := make(map[string]generator.TableMeta)
+	index := make(map[string]int)
+	for _, t := range storedTables {
+		key := database.TableKey(t.DbSchema, t.TableName)
+		index[key] = len(tables)
+		// "-" is the default detail of tables.detail, not a comment
+		comment := strings.TrimSpace(t.Detail.String)
+		if comment == "-" {
+			comment = ""
+		}
+		tables = append(tables, database.Table{
+			Name:    t.TableName,
+			Schema:  t.DbSchema,
+			Comment: comment,
+			IsView:  t.IsView,
+		})
+		metadata[key] = generator.TableMeta{
+			EntityName: t.EntityName.String,
+			Fields:     make(map[string]generator.FieldMeta),
+		}
+	}
+
+	for _, f := range fields {
+		key := database.TableKey(f.DbSchema, f.TableName)
+		i, ok := index[key]
+		if !ok {
+			continue
+		}
+
+		col := database.Column{
+			Name:         f.FieldName,
+			DataType:     f.TypeName.String,
+			IsNullable:   f.IsNull.String == "1",
+			IsPrimaryKey: f.Pk.String == "1",
+			IsForeignKey: f.FTable.String != "",
+			Comment:      f.Detail.String,
+			Position:     int(f.Ordinal.Int32),
+			IsIdentity:   f.IsIdentity.String == "1",
+			IsGenerated:  f.IsGenerated.String == "1",
+			ElementType:  f.ElementType.String,
+			Domain:       f.DomainName.String,
+		}
+		if f.DefaultValue.Valid {
+			val := f.DefaultValue.String
+			col.DefaultValue = &val
+		}
+		if length, err := strconv.Atoi(strings.TrimSpace(f.ChkLength.String)); err == nil && length > 0 {
+			col.MaxLength = &length
+		}
+		if f.ChkEnum.String != "" {
+			if err := json.Unmarshal([]byte(f.ChkEnum.String), &col.Enum); err != nil {
+				return nil, nil, fmt.Errorf("invalid chk_enum of %s.%s: %v", f.TableName, f.FieldName, err)
+			}
+		}
+		col.Min = database.ParseBound(f.ChkMin.String)
+		col.Max = database.ParseBound(f.ChkMax.String)
+		col.Pattern = f.ChkPattern.String
+		if f.NumPrecision.Valid {
+			precision := int(f.NumPrecision.Int32)
+			col.Precision = &precision
+		}
+		if f.NumScale.Valid {
+			scale := int(f.NumScale.Int32)
+			col.Scale = &scale
+		}
+
+		tables[i].Columns = append(tables[i].Columns, col)
+		if col.IsPrimaryKey {
+			tables[i].PrimaryKeys = append(tables[i].PrimaryKeys, col.Name)
+		}
+
+		fm := generator.FieldMeta{
+			Label:      f.Label.String,
+			LabelHelp:  f.LabelHelp.String,
+			OrderList:  int(f.OrderList.Int32),
+			InList:     f.InList != 0,
+			InCrud:     f.InCrud != 0,
+			ValMin:     database.ParseBound(f.ValMin.String),
+			ValMax:     database.ParseBound(f.ValMax.String),
+			ValPattern: f.ValPattern.String,
+			ValMessage: f.ValMessage.String,
+		}
+		if length, err := strconv.Atoi(strings.TrimSpace(f.ValLength.String)); err == nil && length > 0 {
+			fm.ValLength = length
+		}
+		if f.ValEnum.String != "" {
+			if err := json.Unmarshal([]byte(f.ValEnum.String), &fm.ValEnum); err != nil {
+				return nil, nil, fmt.Errorf("invalid val_enum of %s.%s: %v", f.TableName, f.FieldName, err)
+			}
+		}
+		metadata[key].Fields[strings.ToLower(f.FieldName)] = fm
+	}
+
+	// Key columns go in the order of the key, not of the grid: by position,
+	// and by the primary index when one is stored
+	for i := range tables {
+		position := make(map[string]int, len(tables[i].Columns))
+		for _, col := range tables[i].Columns {
+			position[col.Name] = col.Position
+		}
+		keys := tables[i].PrimaryKeys
+		sort.SliceStable(keys, func(a, b int) bool { return position[keys[a]] < position[keys[b]] })
+	}
+
+	for _, r := range rels {
+		i, ok := index[database.TableKey(r.DbSchema, r.TableName)]
+		if !ok {
+			continue
+		}
+		// Rows of the same constraint are the columns of a composite key
+		fks := tables[i].ForeignKeys
+		if n := len(fks); n > 0 && r.ConstraintName.String != "" && fks[n-1].ConstraintName == r.ConstraintName.String {
+			fks[n-1].Columns = append(fks[n-1].Columns, r.FName)
+			fks[n-1].ReferencedColumns = append(fks[n-1].ReferencedColumns, r.FNamePk)
+			continue
+		}
+		// Rows stored before schemar point to the same schema
+		refSchema := r.SchemaR.String
+		if refSchema == "" {
+			refSchema = r.DbSchema
+		}
+		tables[i].ForeignKeys = append(fks, database.ForeignKey{
+			Columns:           []string{r.FName},
+			ReferencedSchema:  refSchema,
+			ReferencedTable:   r.TableR,
+			ReferencedColumns: []string{r.FNamePk},
+			ConstraintName:    r.ConstraintName.String,
+		})
+	}
+
+	for _, idx := range indexes {
+		i, ok := index[database.TableKey(idx.DbSchema, idx.TableName)]
+		if !ok {
+			continue
+		}
+		columns := strings.Split(idx.Columns, ",")
+		tables[i].Indexes = append(tables[i].Indexes, database.Index{
+			Name:    idx.IndexName,
+			Columns: columns,
+			Unique:  idx.IsUnique,
+			Primary: idx.IsPrimary,
+		})
+		if idx.IsPrimary && len(columns) == len(tables[i].PrimaryKeys) {
+			tables[i].PrimaryKeys = columns
+		}
+	}
+
+	return tables, metadata, nil
+}
@@ -39,9 +39,15 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/connections/edit", s.handleConnectionEdit)
 	mux.HandleFunc("/connections/save", s.handleConnectionSave)
 	mux.HandleFunc("/connections/tables", s.handleTablesList)
+	mux.HandleFunc("/connections/tables/fields", s.handleTableFields)
+	mux.HandleFunc("/connections/tables/fields/save", s.handleTableFieldsSave)
 	mux.HandleFunc("/connections/get-tables", s.handleGetInfoTables)
+	mux.HandleFunc("/connections/drift", s.handleDrift)
+	mux.HandleFunc("/connections/ddl", s.handleImportDDL)
 	mux.HandleFunc("/file-templates", s.handleFileTemplatesList)
 	mux.HandleFunc("/connections/generate", s.handleGenerate)
+	mux.HandleFunc("/connections/history", s.handleHistory)
+	mux.HandleFunc("/connections/history/rollback", s.handleHistoryRollback)
 
 	// Subsystems
 	mux.HandleFunc("/subsystems", s.handleSubsystemsList)
@@ -50,6 +56,18 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/subsystems/save", s.handleSubsystemSave)
 	mux.HandleFunc("/subsystems/delete", s.handleSubsystemDelete)
 
+	// Type mappings
+	mux.HandleFunc("/typemaps", s.handleTypeMapsList)
+	mux.HandleFunc("/typemaps/new", s.handleTypeMapNew)
+	mux.HandleFunc("/typemaps/edit", s.handleTypeMapEdit)
+	mux.HandleFunc("/typemaps/save", s.handleTypeMapSave)
+	mux.HandleFunc("/typemaps/delete", s.handleTypeMapDelete)
+
+	// Backups
+	mux.HandleFunc("/backups", s.handleBackups)
+	mux.HandleFunc("/backups/diff", s.handleBackupDiff)
+	mux.HandleFunc("/backups/purge", s.handleBackupsPurge)
+
 	// Endpoints browser
 	mux.HandleFunc("/endpoints", s.handleEndpoints)
 	mux.HandleFunc("/endpoints/tree", s.handleEndpointsTree)
@@ -4,35 +4,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
-	"time"
 	"unicode"
-)
-
-func BackupFileIfExists(filePath string) error {
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil // Archivo no existe, no hay necesidad de backup
-	}
 
-	ext := filepath.Ext(filePath)
-	base := strings.TrimSuffix(filePath, ext)
-	backupPath := fmt.Sprintf("%s.%s.bak", base, time.Now().Format("20060102"))
-
-	return os.Rename(filePath, backupPath)
-}
+	"api-scaffolding/internal/backup"
+)
 
 func EnsureDirectory(dirPath string) error {
 	return os.MkdirAll(dirPath, 0755)
 }
 
-func WriteFileWithBackup(filePath string, content []byte) error {
+// WriteFileWithBackup escribe el archivo moviendo antes el existente a los
+// backups del proyecto.
+func WriteFileWithBackup(backups *backup.Store, filePath string, content []byte) error {
 	// Asegurar que el directorio existe
 	if err := EnsureDirectory(filepath.Dir(filePath)); err != nil {
 		return fmt.Errorf("error creating directory: %v", err)
 	}
 
 	// Hacer backup si el archivo existe
-	if err := BackupFileIfExists(filePath); err != nil {
+	if _, err := backups.Backup(filePath); err != nil {
 		return fmt.Errorf("error backing up file: %v", err)
 	}
 
@@ -0,0 +1,101 @@
+package utils
+
+import "strings"
+
+// Conflict markers written by Merge3, as in git.
+const (
+	ConflictStart = "<<<<<<< current"
+	ConflictSep   = "======="
+	ConflictEnd   = ">>>>>>> generated"
+)
+
+// Merge3 merges the changes made to base in current (the file on disk) and in
+// generated (the new output). Blocks changed on one side only take that side;
+// blocks changed on both sides differently are written between conflict
+// markers. It returns the merged text and the number of conflicts.
+func Merge3(base, current, generated string) (string, int) {
+	o := splitLines(base)
+	a := splitLines(current)
+	b := splitLines(generated)
+	matchA := matchLines(o, a)
+	matchB := matchLines(o, b)
+
+	var out []string
+	conflicts := 0
+	io, ia, ib := 0, 0, 0
+	for {
+		// Next base line kept unchanged on both sides
+		next := io
+		for next < len(o) && (matchA[next] < 0 || matchB[next] < 0) {
+			next++
+		}
+
+		endA, endB := len(a), len(b)
+		if next < len(o) {
+			endA, endB = matchA[next], matchB[next]
+		}
+
+		chunkO, chunkA, chunkB := o[io:next], a[ia:endA], b[ib:endB]
+		switch {
+		case equalLines(chunkA, chunkO):
+			out = append(out, chunkB...)
+		case equalLines(chunkB, chunkO), equalLines(chunkA, chunkB):
+			out = append(out, chunkA...)
+		default:
+			conflicts++
+			out = append(out, ConflictStart)
+			out = append(out, chunkA...)
+			out = append(out, ConflictSep)
+			out = append(out, chunkB...)
+			out = append(out, ConflictEnd)
+		}
+
+		if next == len(o) {
+			break
+		}
+		out = append(out, o[next])
+		io, ia, ib = next+1, endA+1, endB+1
+	}
+
+	merged := strings.Join(out, "\n")
+	if len(out) > 0 && strings.HasSuffix(generated, "\n") {
+		merged += "\n"
+	}
+	return merged, conflicts
+}
+
+// matchLines maps every line of a to the line of b it is paired with in their
+// longest common subsequence, or -1.
+func matchLines(a, b []string) []int {
+	lcs := lcsTable(a, b)
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			match[i] = j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return match
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
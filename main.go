@@ -11,6 +11,7 @@ import (
 	"syscall"
 	"time"
 
+	"api-scaffolding/internal/backup"
 	"api-scaffolding/internal/config"
 	"api-scaffolding/internal/database"
 	"api-scaffolding/internal/server"
@@ -22,6 +23,7 @@ import (
 func main() {
 	var configPath string
 	flag.StringVar(&configPath, "config", "config/.env", "Path to .env configuration file")
+	flag.Usage = usage
 	flag.Parse()
 
 	// Load Configuration
@@ -59,9 +61,24 @@ func main() {
 		log.Printf("Warning during schema initialization: %v", err)
 	}
 
-	// Start Server
+	// One backup policy for every writer of generated files
+	backup.DefaultPolicy = backup.Policy{
+		Dir:       cfg.BackupDir,
+		KeepCount: cfg.BackupKeep,
+		MaxAge:    time.Duration(cfg.BackupMaxAgeDays) * 24 * time.Hour,
+	}
+
 	srv := server.NewServer(cfg, db)
 
+	// Headless subcommands (scan, generate, ...) run once and exit.
+	if flag.NArg() > 0 && flag.Arg(0) != "serve" {
+		code := runCommand(srv, flag.Arg(0), flag.Args()[1:])
+		db.Close()
+		os.Exit(code)
+	}
+
+	// Start Server
+
 	// Create a channel to listen for interrupt signals
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -1,6 +1,6 @@
 version: "1.0"
 method: PUT
-path: "/{{.SubsystemLower}}/{{.TableNameLower}}/:id/custom"
+path: "/{{.SubsystemLower}}/{{.TableNameLower}}/{{.KeyPath}}/custom"
 description: "Funcion custom para actualizar {{.EntityName}}"
 
 auth:
@@ -9,12 +9,16 @@ auth:
 
 params:
   path:
-    - name: id
-      type: int
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar un ID válido"
+    {{- end}}
   
   body:
     # TODO: Definir parámetros específicos del negocio
@@ -37,6 +41,6 @@ response:
 hooks:
   after:
     - type: cache_invalidate
-      keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:{id}"]
+      keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:{{.KeyCache}}"]
     - type: notification
       event: "{{.TableNameLower}}.actualizado"
\ No newline at end of file
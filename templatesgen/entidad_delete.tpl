@@ -1,7 +1,7 @@
 version: "1.0"
 method: DELETE
-path: "/{{.SubsystemLower}}/{{.TableNameLower}}/:id/delete"
-description: "{{if .HasSoftDelete}}Desactivar{{else}}Eliminar{{end}} {{.EntityName}} (soft delete)"
+path: "/{{.SubsystemLower}}/{{.TableNameLower}}/{{.KeyPath}}/delete"
+description: "{{if .HasSoftDelete}}Desactivar{{else}}Eliminar{{end}} {{.EntityName}} (soft delete){{with .TableComment}} - {{.}}{{end}}"
 
 auth:
   required: true
@@ -9,17 +9,24 @@ auth:
 
 params:
   path:
-    - name: id
-      type: int
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar ID válido a {{if .HasSoftDelete}}desactivar{{else}}desactivar{{end}}"
+    {{- end}}
 
 commands:
+  # region:custom-commands
+  # endregion
+
   # Verificar que el registro existe
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE id = :id"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}}"
     condition: "count = 0"
     on_true:
       action: stop
@@ -29,7 +36,7 @@ commands:
   {{if .HasActiveField}}
   # Verificar si ya esta inactivo
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE id = :id AND activo = true"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}} AND activo = true"
     condition: "count = 0"
     on_true:
       action: stop
@@ -41,17 +48,17 @@ commands:
   # Soft delete (marcar como inactivo o con deleted_at)
   - type: exec
     sql: |
-      UPDATE {{.TableName}} SET {{if .HasActiveField}}activo = false,{{end}}deleted_at = {{if eq .DBDriver "postgres"}}NOW(){{else}}NOW(){{end}} {{if .HasDeletedBy}}, deleted_by = :user_id{{end}} WHERE id = :id
+      UPDATE {{.QualifiedName}} SET {{if .HasActiveField}}activo = false,{{end}}deleted_at = {{if eq .DBDriver "postgres"}}NOW(){{else}}NOW(){{end}} {{if .HasDeletedBy}}, deleted_by = :user_id{{end}} WHERE {{.KeyWhere}}
   {{else if .HasActiveField}}
   # Marcar como inactivo si existe campo activo
   - type: exec
     sql: |
-      UPDATE {{.TableName}} SET activo = false WHERE id = :id
+      UPDATE {{.QualifiedName}} SET activo = false WHERE {{.KeyWhere}}
   {{else}}
   # Hard delete (eliminar permanentemente)
   - type: exec
     sql: |
-      DELETE FROM {{.TableName}} WHERE id = :id
+      DELETE FROM {{.QualifiedName}} WHERE {{.KeyWhere}}
   {{end}}
 
 response:
@@ -62,9 +69,11 @@ response:
 hooks:
   after:
     - type: cache_invalidate
-      keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:{{"{{."}}id{{"}}"}}"]
+      keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:{{.KeyCache}}"]
     - type: notification
       event: "{{.TableNameLower}}.{{if .HasSoftDelete}}desactivado{{else}}eliminado{{end}}"
+    # region:custom-hooks
+    # endregion
 
 audit:
   enabled: true
\ No newline at end of file
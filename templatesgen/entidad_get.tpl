@@ -1,7 +1,7 @@
 version: "1.0"
 method: GET
-path: "/{{.SubsystemLower}}/{{.TableNameLower}}/:id/get"
-description: "Obtener {{.EntityName}} por ID"
+path: "/{{.SubsystemLower}}/{{.TableNameLower}}/{{.KeyPath}}/get"
+description: "Obtener {{.EntityName}} por ID{{with .TableComment}} - {{.}}{{end}}"
 
 auth:
   required: true
@@ -9,20 +9,27 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar ID válido"
+    {{- end}}
 
 commands:
+  # region:custom-commands
+  # endregion
+
   # Obtener {{.EntityNameTitle}}
   - type: query
     sql: |
       SELECT *
-      FROM {{.TableName}} 
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}{{- if .HasSoftDelete}} AND activo = true {{- end}}
+      FROM {{.QualifiedName}} 
+      WHERE {{.KeyWhere}}{{- if .HasSoftDelete}} AND activo = true {{- end}}
     returns: "single"
     on_result:
       if_not_found:
@@ -66,7 +73,7 @@ response:
 cache:
   enabled: true
   ttl: 300
-  key: "{{.TableNameLower}}:{{"{{"}}.id{{"}}"}}"
+  key: "{{.TableNameLower}}:{{.KeyCache}}"
 
 audit:
   enabled: true
\ No newline at end of file
@@ -1,7 +1,7 @@
 version: "1.0"
 method: GET
 path: "/{{.SubsystemLower}}/{{.TableNameLower}}/list"
-description: "Lista de {{.EntityName}}"
+description: "Lista de {{.EntityName}}{{with .TableComment}} - {{.}}{{end}}"
 
 auth:
   required: true
@@ -23,19 +23,45 @@ params:
     - name: search
       type: string
       required: false
+    {{- if .IndexedListFields}}
+    - name: sort
+      type: string
+      required: false
+      validation:
+        pattern: '^({{range $i, $f := .IndexedListFields}}{{if $i}}|{{end}}{{$f.Name}}{{end}})$'
+    - name: order
+      type: string
+      required: false
+      default: "asc"
+      validation:
+        pattern: '^(asc|desc)$'
+    {{- range .IndexedListFields}}
+    {{- if not .IsPrimaryKey}}
+    - name: {{.NameSnake}}
+      type: {{.Type}}
+      required: false
+    {{- end}}
+    {{- end}}
+    {{- end}}
 
 commands:
+  # region:custom-commands
+  # endregion
+
   - type: query
     sql: |
-      SELECT * 
-      FROM {{.TableName}} 
-      {{- if .HasSoftDelete}}
-      WHERE activo = true 
-      {{- end}}
+      SELECT {{.ListColumns}} 
+      FROM {{.QualifiedName}} 
+      WHERE {{if .HasSoftDelete}}activo = true{{else}}1 = 1{{end}}
       {{- if .HasSearch}}
       AND ({{range .SearchFields}}{{.}} ILIKE '%' || COALESCE(:search, ''){{break}}{{else}}id{{end}})
       {{- end}}
-      ORDER BY {{range .PrimaryKeys}}{{.}}{{break}}{{else}}id{{end}} ASC LIMIT :limit OFFSET :offset
+      {{- range .IndexedListFields}}
+      {{- if not .IsPrimaryKey}}
+      {{ print "{{if ." .NameSnake "}}" }}AND {{.Name}} = :{{.NameSnake}}{{ print "{{end}}" }}
+      {{- end}}
+      {{- end}}
+      ORDER BY {{if .IndexedListFields}}{{ print "{{if .sort}}{{.sort}} {{.order}}{{else}}" }}{{.KeyColumns}} ASC{{ print "{{end}}" }}{{else}}{{.KeyColumns}} ASC{{end}} LIMIT :limit OFFSET :offset
     #AND ( campo1 ILIKE '%' || COALESCE(:search, '') || '%'
     #      OR campo2 ILIKE '%' || COALESCE(:search, '') || '%' 
     #      OR campo3 ILIKE '%' || COALESCE(:search, '') || '%'
@@ -52,7 +78,7 @@ response:
   structure:
     type: paginated
     pagination:
-      total_query: "SELECT COUNT(*) FROM {{.TableName}} WHERE activo = true"
+      total_query: "SELECT COUNT(*) FROM {{.QualifiedName}} WHERE {{if .HasSoftDelete}}activo = true{{else}}1 = 1{{end}}{{range .IndexedListFields}}{{if not .IsPrimaryKey}}{{ print "{{if ." .NameSnake "}}" }} AND {{.Name}} = :{{.NameSnake}}{{ print "{{end}}" }}{{end}}{{end}}"
       # AND ( nombre ILIKE '%' || COALESCE(:search, '') || '%'
       #      OR apellido ILIKE '%' || COALESCE(:search, '') || '%' 
       #      OR username ILIKE '%' || COALESCE(:search, '') || '%'
@@ -76,5 +102,5 @@ response:
 cache:
   enabled: true
   ttl: 60
-  key: "{{.TableNameLower}}:list:{{ "{{" }}.page{{ "}}" }}:{{ "{{" }}.limit{{ "}}" }}:{{ "{{" }}.search{{ "}}" }}"
+  key: "{{.TableNameLower}}:list:{{ "{{" }}.page{{ "}}" }}:{{ "{{" }}.limit{{ "}}" }}:{{ "{{" }}.search{{ "}}" }}{{if .IndexedListFields}}:{{ "{{" }}.sort{{ "}}" }}:{{ "{{" }}.order{{ "}}" }}{{range .IndexedListFields}}{{if not .IsPrimaryKey}}:{{ "{{" }}.{{.NameSnake}}{{ "}}" }}{{end}}{{end}}{{end}}"
 
@@ -1,7 +1,7 @@
 version: "1.0"
 method: POST
 path: "/{{.SubsystemLower}}/{{.TableNameLower}}/new"
-description: "Crear nuevo {{.EntityName}}"
+description: "Crear nuevo {{.EntityName}}{{with .TableComment}} - {{.}}{{end}}"
 
 auth:
   required: true
@@ -10,17 +10,20 @@ auth:
 params:
   body:
     {{- $hasPassword := false }}
-    {{- range .Fields}}
-    {{- if and (not .IsPrimaryKey) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at") (ne .Name "deleted_by") (ne .Name "created_by") (ne .Name "updated_by")}}
+    {{- range .CrudFields}}
+    {{- if and (or (not .IsPrimaryKey) $.HasCompositeKey) (not .IsIdentity) (not .IsGenerated) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at") (ne .Name "deleted_by") (ne .Name "created_by") (ne .Name "updated_by")}}
 
     - name: {{.NameSnake}}
       type: {{.Type}}
+      {{- with .Format}}
+      format: {{.}}
+      {{- end}}
       required: {{.IsRequired}}
       {{- if .Validation}}
       validation:
         {{- range $key, $value := .Validation}}
         {{- if (eq $key "pattern")}}
-        {{$key}}: '{{printf "%v" $value}}'
+        {{$key}}: '{{yamlEscape $value}}'
         {{- else}}
         {{$key}}: {{printf "%v" $value}}
         {{- end}}
@@ -33,27 +36,43 @@ params:
       {{- if .Default}}
       default: "{{printf "%v" .Default}}"
       {{- end}}
-      error_message: "{{.NamePascal}} inválido"
+      {{- if .LabelHelp}}
+      description: "{{.LabelHelp}}"
+      {{- end}}
+      error_message: "{{.Label}} inválido"
     {{- end}}
     {{- end}}
 
 commands:
+  # region:custom-commands
   ## Validar CODIGO único
   #- type: validation
-  #  sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE code = :code"
+  #  sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE code = :code"
   #  condition: "count > 0"
   #  on_true:
   #    action: stop
   #    http_code: 409
   #    message: "Ya existe ese codigo para la {{.EntityName}}"
+  # endregion
+  {{- range .UniqueKeys}}
+
+  # Validar {{.Name}} único
+  - type: validation
+    sql: "SELECT COUNT(*) as count FROM {{$.QualifiedName}} WHERE {{range $i, $f := .Fields}}{{if $i}} AND {{end}}{{$f.Name}} = :{{$f.NameSnake}}{{end}}"
+    condition: "count > 0"
+    on_true:
+      action: stop
+      http_code: 409
+      message: "Ya existe un {{$.EntityName}} con ese {{range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Label}}{{end}}"
+  {{- end}}
 
   # Insertar {{.EntityName}}
   - type: exec
     sql: |
-      INSERT INTO {{.TableName}} (
+      INSERT INTO {{.QualifiedName}} (
         {{- $first := true}}
-        {{- range .Fields}}
-        {{- if and (not .IsPrimaryKey) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at") (ne .Name "deleted_by")}}
+        {{- range .CrudFields}}
+        {{- if and (or (not .IsPrimaryKey) $.HasCompositeKey) (not .IsIdentity) (not .IsGenerated) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at") (ne .Name "deleted_by")}}
         {{- if $first}}{{$first = false}}{{else}},{{end}}
         {{.Name}}
         {{- end}}
@@ -63,8 +82,8 @@ commands:
         {{- end}}
       ) VALUES (
         {{- $first := true}}
-        {{- range .Fields}}
-        {{- if and (not .IsPrimaryKey) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at") (ne .Name "deleted_by")}}
+        {{- range .CrudFields}}
+        {{- if and (or (not .IsPrimaryKey) $.HasCompositeKey) (not .IsIdentity) (not .IsGenerated) (ne .Name "created_at") (ne .Name "updated_at") (ne .Name "deleted_at") (ne .Name "deleted_by")}}
         {{- if $first}}{{$first = false}}{{else}},{{end}}
         :{{.NameSnake}}
         {{- end}}
@@ -105,6 +124,8 @@ hooks:
       keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:search:*"]
     - type: notification
       event: "{{.TableNameLower}}.creado"
+    # region:custom-hooks
+    # endregion
 
 audit:
   enabled: true
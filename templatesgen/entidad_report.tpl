@@ -1,7 +1,7 @@
 version: "1.0"
 method: GET
 path: "/{{.SubsystemLower}}/{{.TableNameLower}}/report"
-description: "Reporte de {{.TableNameLower}} activos"
+description: "Reporte de {{.TableNameLower}} activos{{with .TableComment}} - {{.}}{{end}}"
 
 auth:
   required: true
@@ -73,7 +73,7 @@ report:
         {{- if $first}}{{$first = false}}{{else}},{{end}}
         {{.SQLExpr}}
         {{- end}}
-      FROM {{.TableName}} {{.TableAlias | default (printf "%s" .TableNameLower)}}
+      FROM {{.QualifiedName}} {{.TableAlias | default (printf "%s" .TableNameLower)}}
       {{- range .ReportJoins}}
       {{.}}
       {{- end}}
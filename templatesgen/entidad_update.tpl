@@ -1,7 +1,7 @@
 version: "1.0"
 method: PUT
-path: "/{{.SubsystemLower}}/{{.TableNameLower}}/:id/update"
-description: "Actualizar {{.EntityName}}"
+path: "/{{.SubsystemLower}}/{{.TableNameLower}}/{{.KeyPath}}/update"
+description: "Actualizar {{.EntityName}}{{with .TableComment}} - {{.}}{{end}}"
 
 auth:
   required: true
@@ -9,26 +9,33 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar un ID válido"
+    {{- end}}
   
   body:
     {{- $hasPassword := false }}
-    {{- range .Fields}}
-    {{- if shouldIncludeInUpdate .Name}}
+    {{- range .CrudFields}}
+    {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (shouldIncludeInUpdate .Name)}}
   
     - name: "{{.NameSnake}}"
       type: "{{.Type}}"
+      {{- with .Format}}
+      format: "{{.}}"
+      {{- end}}
       required: false
       {{- if .Validation}}
       validation:
         {{- range $key, $value := .Validation}}
         {{- if (eq $key "pattern")}}
-        {{$key}}: '{{printf "%v" $value}}'
+        {{$key}}: '{{yamlEscape $value}}'
         {{- else}}
         {{$key}}: {{printf "%v" $value}}
         {{- end}}
@@ -38,29 +45,45 @@ params:
       constraints: [has_lower, has_upper, has_number, has_special]
       {{- $hasPassword = true }}
       {{- end}}
-      {{- if .Comment}}
+      {{- if .LabelHelp}}
+      description: "{{.LabelHelp}}"
+      {{- else if .Comment}}
       description: "{{.Comment}}"
       {{- end}}
     {{- end}}
     {{- end}}
 
 commands:
+  # region:custom-commands
+  # endregion
+
   # Verificar que existe
   - type: validation
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}{{if .HasSoftDelete}} AND activo = true{{end}}"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}}{{if .HasSoftDelete}} AND activo = true{{end}}"
     condition: "count = 0"
     on_true:
       action: stop
       http_code: 404
       message: "{{.EntityName}} no encontrado"
+  {{- range .UniqueKeys}}
+
+  # Validar {{.Name}} único
+  - type: validation
+    sql: "SELECT COUNT(*) as count FROM {{$.QualifiedName}} WHERE {{range $i, $f := .Fields}}{{if $i}} AND {{end}}{{$f.Name}} = :{{$f.NameSnake}}{{end}} AND {{$.KeyNotWhere}}"
+    condition: "count > 0"
+    on_true:
+      action: stop
+      http_code: 409
+      message: "Ya existe un {{$.EntityName}} con ese {{range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Label}}{{end}}"
+  {{- end}}
   
   # Actualizar
   - type: exec
     sql: |
-      UPDATE {{.TableName}} SET
+      UPDATE {{.QualifiedName}} SET
       {{- $first := true}}
-      {{- range .Fields}}
-        {{- if shouldIncludeInUpdate .Name}}
+      {{- range .CrudFields}}
+        {{- if and (not .IsPrimaryKey) (not .IsIdentity) (not .IsGenerated) (shouldIncludeInUpdate .Name)}}
       {{ print "{{if ." .Name "}}" }}{{.Name}} = :{{.NameSnake}},{{ print "{{end}}" }}
           {{- $first = false}}
         {{- end}}
@@ -68,7 +91,7 @@ commands:
       {{- if .HasAuditFields}}
       updated_at = {{nowFunc}}
       {{- end}}
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
+      WHERE {{.KeyWhere}}
       RETURNING {{$first = true}}
       {{- range .Fields}}
         {{- if and (ne .Name "password_hash") (ne .Name "password") (ne .Name "created_at") (ne .Name "deleted_at") (ne .Name "deleted_by")}}
@@ -90,6 +113,8 @@ response:
 hooks:
   after:
     - type: cache_invalidate
-      keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:{id}"]
+      keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:{{.KeyCache}}"]
     - type: notification
-      event: "{{.TableNameLower}}.actualizado"
\ No newline at end of file
+      event: "{{.TableNameLower}}.actualizado"
+    # region:custom-hooks
+    # endregion
\ No newline at end of file
@@ -1,6 +1,6 @@
 version: "1.0"
 method: POST
-path: "/{{.SubsystemLower}}/{{.TableNameLower}}/:id/upload-avatar-s3"
+path: "/{{.SubsystemLower}}/{{.TableNameLower}}/{{.KeyPath}}/upload-avatar-s3"
 description: "Subir archivo de avatar del {{.EntityName}} a S3"
 
 auth:
@@ -9,12 +9,16 @@ auth:
 
 params:
   path:
-    - name: id
-      type: {{range .PrimaryKeys}}{{if eq . "id"}}int{{else}}string{{end}}{{break}}{{else}}int{{end}}
+    {{- range .KeyParams}}
+    - name: {{.Name}}
+      type: {{.Type}}
       required: true
+      {{- if or (eq .Type "int") (eq .Type "int64")}}
       validation:
         min: 1
+      {{- end}}
       error_message: "Debe proporcionar un ID válido"
+    {{- end}}
 
   file:
     - name: archivo
@@ -30,7 +34,7 @@ commands:
   # Verificar que el {{.EntityName}} existe
   - type: validation
     db: {{.DBName | default "main"}}
-    sql: "SELECT COUNT(*) as count FROM {{.TableName}} WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}{{if .HasSoftDelete}} AND activo = true{{end}}"
+    sql: "SELECT COUNT(*) as count FROM {{.QualifiedName}} WHERE {{.KeyWhere}}{{if .HasSoftDelete}} AND activo = true{{end}}"
     condition: "count = 0"
     on_true:
       action: stop
@@ -46,7 +50,7 @@ commands:
       credentials:
         access_key: "env:AWS_ACCESS_KEY_ID"
         secret_key: "env:AWS_SECRET_ACCESS_KEY"
-      key_template: "{{.TableNameLower}}/:id/avatar/:uuid:ext"
+      key_template: "{{.TableNameLower}}/{{.KeyPath}}/avatar/:uuid:ext"
       acl: "private"
       content_disposition: "inline"
       storage_class: "STANDARD"
@@ -61,13 +65,13 @@ commands:
   - type: exec
     db: {{.DBName | default "main"}}
     sql: |
-      UPDATE {{.TableName}} SET
+      UPDATE {{.QualifiedName}} SET
         avatar_s3_key = :file_key,
         avatar_url = :file_url,
         updated_at = NOW(),
         updated_by = :user_id
-      WHERE {{range .PrimaryKeys}}{{.}} = :id{{break}}{{else}}id = :id{{end}}
-      RETURNING {{range .PrimaryKeys}}{{.}}{{break}}{{else}}id{{end}}, username, nombre, apellido, avatar_url, updated_at
+      WHERE {{.KeyWhere}}
+      RETURNING {{.KeyColumns}}, username, nombre, apellido, avatar_url, updated_at
 
 response:
   success:
@@ -78,9 +82,10 @@ response:
     message: "Error al subir archivo"
 
 map:
-  {{range .PrimaryKeys}}{{.}}: "{{.}}"
-  {{break}}{{else}}id: "id"
-  {{end}}username: "username"
+  {{- range .KeyParams}}
+  {{.Column}}: "{{.Column}}"
+  {{- end}}
+  username: "username"
   nombre: "nombre"
   apellido: "apellido"
   avatar_url: "avatar_url"
@@ -89,7 +94,7 @@ map:
 hooks:
   after:
     - type: cache_invalidate
-      keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:{{ "{{" }}.id{{ "}}" }}"]
+      keys: ["{{.TableNameLower}}:list:*", "{{.TableNameLower}}:{{.KeyCache}}"]
     - type: notification
       event: "{{.TableNameLower}}.avatar_s3_actualizado"
 